@@ -2,9 +2,12 @@ package app
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/orellazri/renderkit/internal/engines"
@@ -13,8 +16,19 @@ import (
 )
 
 type App struct {
-	cliApp *cli.App
-	engine engines.Engine
+	cliApp    *cli.App
+	engine    engines.Engine
+	stdin     io.Reader
+	stderr    io.Writer
+	allowExec bool
+	traceData bool
+	httpCache *httpCache
+
+	interpolate    bool
+	valuesManifest *valuesManifest
+	dataSchema     *dataSchema
+
+	dataOrigins map[string][]dataOrigin // Every value set for each top-level key of the merged data, in merge order
 }
 
 func NewApp(version string) *App {
@@ -62,7 +76,7 @@ func NewApp(version string) *App {
 		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
 			Name:    "datasource",
 			Aliases: []string{"ds"},
-			Usage:   "Datasource to use for rendering (scheme://path)",
+			Usage:   "Datasource to use for rendering (scheme://path). Use stdin:// to read data from stdin",
 		}),
 		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
 			Name:  "data",
@@ -79,11 +93,55 @@ func NewApp(version string) *App {
 				return nil
 			},
 		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "values-manifest",
+			Usage: "Manifest declaring the keys templates expect (e.g. renderkit.values.yaml), with their descriptions, types, defaults and whether they are required",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "schema",
+			Usage: "JSON Schema (JSON or YAML) to validate the merged data against before rendering. Its default values are applied to the data",
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:        "interpolate",
+			Usage:       "Resolve ${key} references in the values of the merged data to other keys, e.g. url: \"https://${domain}/api\"",
+			DefaultText: "false",
+		}),
 		altsrc.NewBoolFlag(&cli.BoolFlag{
 			Name:        "allow-duplicate-keys",
 			Usage:       "Allow duplicate keys in datasources. If set, the last value found will be used",
 			DefaultText: "false",
 		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:        "trace-data",
+			Usage:       "Print to stderr which datasource, file and line set each top-level key of the data, and what it overrode",
+			DefaultText: "false",
+		}),
+		// Not read from the configuration file, so that a shared configuration cannot run commands by itself
+		&cli.BoolFlag{
+			Name:        "allow-exec",
+			Usage:       "Allow exec:// datasources, which run local commands and read their output. Can only be set on the command line",
+			DefaultText: "false",
+		},
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:        "http-cache",
+			Usage:       "Cache HTTP datasource responses on disk and revalidate them with ETag/If-Modified-Since",
+			DefaultText: "false",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:        "http-cache-dir",
+			Usage:       "Directory to store cached HTTP datasource responses in",
+			DefaultText: "<user cache dir>/renderkit/http",
+		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:        "http-cache-ttl",
+			Usage:       "How long cached HTTP responses without a Cache-Control max-age are used without revalidating them",
+			DefaultText: "0s",
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:        "http-offline-fallback",
+			Usage:       "Use the last cached HTTP response when the server is unreachable",
+			DefaultText: "false",
+		}),
 	}
 
 	app := &cli.App{
@@ -93,6 +151,42 @@ func NewApp(version string) *App {
 		Before:  altsrc.InitInputSourceWithContext(flags, altsrc.NewYamlSourceFromFlagFunc("config")),
 		Action:  a.run,
 		Version: version,
+		Commands: []*cli.Command{
+			{
+				Name:   "data",
+				Usage:  "Print the merged data that templates are rendered with. Uses the same flags as rendering, given before the command",
+				Action: a.runData,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: fmt.Sprintf("Format to print the data in (%s)", strings.Join(dumpFormats, ", ")),
+						Value: "yaml",
+						Action: func(cCtx *cli.Context, value string) error {
+							if !slices.Contains(dumpFormats, value) {
+								return fmt.Errorf("format %s is not supported. supported formats: %s", value, strings.Join(dumpFormats, ", "))
+							}
+							return nil
+						},
+					},
+					&cli.BoolFlag{
+						Name:  "docs",
+						Usage: "Print the documentation of the keys declared in the values manifest instead of the data",
+					},
+					&cli.StringSliceFlag{
+						Name:  "redact",
+						Usage: "Redact the values of keys matching a glob pattern, matched against the key and its dotted path (e.g. *password*, db.*)",
+					},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:      "explain",
+						Usage:     "Show the value of a key, the datasource, file and line it came from, and every value it overrode",
+						ArgsUsage: "<key path>",
+						Action:    a.runDataExplain,
+					},
+				},
+			},
+		},
 	}
 
 	a.cliApp = app
@@ -103,12 +197,26 @@ func (a *App) Run(args []string) error {
 	return a.cliApp.Run(args)
 }
 
+func (a *App) stdinReader() io.Reader {
+	if a.stdin != nil {
+		return a.stdin
+	}
+	return os.Stdin
+}
+
+func (a *App) stderrWriter() io.Writer {
+	if a.stderr != nil {
+		return a.stderr
+	}
+	return os.Stderr
+}
+
 func (a *App) run(cCtx *cli.Context) error {
 	var inputString string
 
-	// Read from stdin into an input string; and if empty, from input flag
+	// Read from stdin into an input string, unless stdin is used as a datasource; and if empty, from input flag
 	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) == 0 {
+	if (stat.Mode()&os.ModeCharDevice) == 0 && countStdinDatasources(cCtx.StringSlice("datasource")) == 0 {
 		var stdinBytes []byte
 		scanner := bufio.NewScanner(os.Stdin)
 		for scanner.Scan() {
@@ -143,14 +251,9 @@ func (a *App) run(cCtx *cli.Context) error {
 		a.engine = eng
 	}
 
-	datasourceUrls, err := a.parseDatasourceUrls(cCtx.StringSlice("datasource"))
+	data, err := a.loadData(cCtx)
 	if err != nil {
-		return fmt.Errorf("parse datasource URLs: %s", err)
-	}
-
-	data, err := a.loadDatasources(datasourceUrls, cCtx.StringSlice("data"), cCtx.Bool("allow-duplicate-keys"))
-	if err != nil {
-		return fmt.Errorf("load datasources: %s", err)
+		return err
 	}
 
 	excludePaths := []string{}
@@ -176,3 +279,98 @@ func (a *App) run(cCtx *cli.Context) error {
 
 	return nil
 }
+
+func (a *App) runData(cCtx *cli.Context) error {
+	if cCtx.NArg() > 0 {
+		return fmt.Errorf("unknown command %s", cCtx.Args().First())
+	}
+
+	if cCtx.Bool("docs") {
+		manifestPath := cCtx.String("values-manifest")
+		if manifestPath == "" {
+			return errors.New("--docs requires a values manifest, set with --values-manifest")
+		}
+		manifest, err := loadValuesManifest(manifestPath)
+		if err != nil {
+			return fmt.Errorf("load values manifest: %s", err)
+		}
+		return manifest.writeDocs(cCtx.App.Writer)
+	}
+
+	data, err := a.loadData(cCtx)
+	if err != nil {
+		return err
+	}
+
+	if err := dumpData(cCtx.App.Writer, data, cCtx.String("format"), cCtx.StringSlice("redact")); err != nil {
+		return fmt.Errorf("print data: %s", err)
+	}
+	return nil
+}
+
+func (a *App) runDataExplain(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return fmt.Errorf("expected exactly one key path, got %d", cCtx.NArg())
+	}
+
+	data, err := a.loadData(cCtx)
+	if err != nil {
+		return err
+	}
+
+	// The redact flag belongs to the data command and is found through the context lineage
+	return a.explainData(cCtx.App.Writer, data, cCtx.Args().First(), cCtx.StringSlice("redact"))
+}
+
+// loadData loads and merges the datasources and extra data, checks them against the values manifest and the schema
+// if they are set
+func (a *App) loadData(cCtx *cli.Context) (map[string]any, error) {
+	a.allowExec = cCtx.Bool("allow-exec")
+	a.traceData = cCtx.Bool("trace-data")
+	a.interpolate = cCtx.Bool("interpolate")
+
+	if manifestPath := cCtx.String("values-manifest"); manifestPath != "" {
+		manifest, err := loadValuesManifest(manifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("load values manifest: %s", err)
+		}
+		a.valuesManifest = manifest
+	}
+	if schemaPath := cCtx.String("schema"); schemaPath != "" {
+		schema, err := loadDataSchema(schemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("load schema: %s", err)
+		}
+		a.dataSchema = schema
+	}
+
+	if cCtx.Bool("http-cache") {
+		cache, err := newHttpCache(cCtx.String("http-cache-dir"), cCtx.Duration("http-cache-ttl"), cCtx.Bool("http-offline-fallback"))
+		if err != nil {
+			return nil, fmt.Errorf("create HTTP cache: %s", err)
+		}
+		a.httpCache = cache
+	}
+
+	// Checked here as well as in validateFlags, since the data commands do not validate the rendering flags
+	if countStdinDatasources(cCtx.StringSlice("datasource")) > 1 {
+		return nil, ErrMultipleStdinDatasources
+	}
+	datasourceUrls, err := a.parseDatasourceUrls(cCtx.StringSlice("datasource"))
+	if err != nil {
+		return nil, fmt.Errorf("parse datasource URLs: %s", err)
+	}
+
+	data, err := a.loadDatasources(datasourceUrls, cCtx.StringSlice("data"), cCtx.Bool("allow-duplicate-keys"))
+	if err != nil {
+		return nil, fmt.Errorf("load datasources: %s", err)
+	}
+
+	if a.dataSchema != nil {
+		if err := a.validateSchema(a.dataSchema, data); err != nil {
+			return nil, fmt.Errorf("validate data: %s", err)
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// dumpFormats are the formats the merged data can be printed in
+var dumpFormats = []string{"json", "yaml", "toml"}
+
+const redactedValue = "<redacted>"
+
+// dumpData writes the data in the given format with sorted keys, replacing the values of keys that match
+// any of the redact patterns. TOML has no null, so null values are left out of it.
+func dumpData(w io.Writer, data map[string]any, format string, redactPatterns []string) error {
+	if err := checkRedactPatterns(redactPatterns); err != nil {
+		return err
+	}
+	redacted := redactData(data, "", redactPatterns).(map[string]any)
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(redacted)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(redacted); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(redacted)
+	default:
+		return fmt.Errorf("unsupported format %s, supported formats: %s", format, strings.Join(dumpFormats, ", "))
+	}
+}
+
+func checkRedactPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid redact pattern %q: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// redactData returns a copy of the value with the values of matching keys redacted. Patterns are matched against
+// both the key and its dotted path (e.g. password, *_token or db.*), and list elements share the path of their list.
+func redactData(value any, keyPath string, patterns []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			p := k
+			if keyPath != "" {
+				p = keyPath + "." + k
+			}
+			if matchesRedactPattern(k, p, patterns) {
+				m[k] = redactedValue
+			} else {
+				m[k] = redactData(e, p, patterns)
+			}
+		}
+		return m
+	case []any:
+		l := make([]any, len(v))
+		for i, e := range v {
+			l[i] = redactData(e, keyPath, patterns)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+func matchesRedactPattern(key string, keyPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, keyPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSelected redacts the value at the selector path like dumpData would redact it in the whole data, so a value
+// under a matching key is redacted entirely. Indexes are left out of the dotted path, as list elements share the path
+// of their list.
+func redactSelected(value any, path []selectorSegment, patterns []string) any {
+	keyPath := ""
+	for _, segment := range path {
+		if segment.isIndex {
+			continue
+		}
+		if keyPath == "" {
+			keyPath = segment.key
+		} else {
+			keyPath += "." + segment.key
+		}
+		if matchesRedactPattern(segment.key, keyPath, patterns) {
+			return redactedValue
+		}
+	}
+	return redactData(value, keyPath, patterns)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpData(t *testing.T) {
+	data := map[string]any{
+		"name": "app",
+		"db":   map[string]any{"host": "localhost", "password": "secret"},
+		"services": []any{
+			map[string]any{"name": "web", "api_token": "abc"},
+		},
+	}
+	redact := []string{"*password*", "*_token"}
+
+	var out bytes.Buffer
+	require.NoError(t, dumpData(&out, data, "yaml", redact))
+	require.Equal(t, `db:
+  host: localhost
+  password: <redacted>
+name: app
+services:
+  - api_token: <redacted>
+    name: web
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, dumpData(&out, data, "json", nil))
+	require.Equal(t, `{
+  "db": {
+    "host": "localhost",
+    "password": "secret"
+  },
+  "name": "app",
+  "services": [
+    {
+      "api_token": "abc",
+      "name": "web"
+    }
+  ]
+}
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, dumpData(&out, data, "toml", []string{"db.*"}))
+	require.Equal(t, `name = 'app'
+
+[db]
+host = '<redacted>'
+password = '<redacted>'
+
+[[services]]
+api_token = 'abc'
+name = 'web'
+`, out.String())
+
+	// The data itself is not redacted
+	require.Equal(t, "secret", data["db"].(map[string]any)["password"])
+
+	require.ErrorContains(t, dumpData(&out, data, "xml", nil), "unsupported format xml")
+	require.ErrorContains(t, dumpData(&out, data, "json", []string{"["}), "invalid redact pattern")
+}
+
+func TestDataCommand(t *testing.T) {
+	valuesPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesPath, []byte("db:\n  password: secret\n"), os.ModePerm))
+
+	var out bytes.Buffer
+	app := NewApp("test")
+	app.cliApp.Writer = &out
+	err := app.Run([]string{"", "--datasource", valuesPath, "--data", "name=app", "data", "--format", "json", "--redact", "password"})
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"db\": {\n    \"password\": \"<redacted>\"\n  },\n  \"name\": \"app\"\n}\n", out.String())
+
+	err = app.Run([]string{"", "--datasource", valuesPath, "data", "--format", "xml"})
+	require.ErrorContains(t, err, "format xml is not supported")
+}
+
+func TestDataCommandMultipleStdinDatasources(t *testing.T) {
+	app := NewApp("test")
+	app.cliApp.Writer = &bytes.Buffer{}
+	err := app.Run([]string{"", "--datasource", "stdin://", "--datasource", "STDIN://?format=json", "data"})
+	require.ErrorIs(t, err, ErrMultipleStdinDatasources)
+
+	err = app.Run([]string{"", "--datasource", "stdin://", "--datasource", "stdin://", "data", "explain", "key"})
+	require.ErrorIs(t, err, ErrMultipleStdinDatasources)
+}
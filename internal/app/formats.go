@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/orellazri/renderkit/internal/datasources"
+)
+
+// extensionFormats maps file extensions to the format used to load them
+var extensionFormats = map[string]string{
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".json":       "json",
+	".jsonl":      "jsonl",
+	".ndjson":     "jsonl",
+	".toml":       "toml",
+	".env":        "env",
+	".xml":        "xml",
+	".hcl":        "hcl",
+	".tfvars":     "hcl",
+	".ini":        "ini",
+	".properties": "properties",
+}
+
+// mediaTypeFormats maps HTTP media types to the format used to load them
+var mediaTypeFormats = map[string]string{
+	"application/json":        "json",
+	"application/toml":        "toml",
+	"application/yaml":        "yaml",
+	"text/yaml":               "yaml",
+	"text/x-yaml":             "yaml",
+	"application/x-yaml":      "yaml",
+	"application/xml":         "xml",
+	"text/xml":                "xml",
+	"application/jsonl":       "jsonl",
+	"application/x-ndjson":    "jsonl",
+	"application/x-jsonlines": "jsonl",
+}
+
+// newFormatDatasource creates a datasource that loads data of the given format from r.
+// The name is used to identify the source in error messages.
+func newFormatDatasource(format string, r io.Reader, name string, url *url.URL) (datasources.Datasource, error) {
+	switch format {
+	case "yaml":
+		return datasources.NewMultiDocumentYamlDatasource(r, yamlDocuments(url)), nil
+	case "json":
+		return datasources.NewJsonDatasource(r), nil
+	case "jsonl":
+		return datasources.NewJsonLinesDatasource(r), nil
+	case "toml":
+		return datasources.NewTomlDatasource(r), nil
+	case "env":
+		return datasources.NewEnvFileDatasource(r), nil
+	case "xml":
+		return datasources.NewXmlDatasource(r), nil
+	case "hcl":
+		return datasources.NewHclDatasource(r, name), nil
+	case "ini":
+		return datasources.NewIniDatasource(r), nil
+	case "properties":
+		nested, err := parseBoolOption(url, "nested")
+		if err != nil {
+			return nil, err
+		}
+		return datasources.NewPropertiesDatasource(r, nested), nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// httpFormat determines the format of an HTTP response. An explicit format option takes precedence,
+// followed by the response's content type and finally the extension of the URL path.
+func httpFormat(url *url.URL, res *http.Response) (string, error) {
+	if format := url.Query().Get("format"); format != "" {
+		return format, nil
+	}
+
+	mt, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if format, ok := mediaTypeFormats[mt]; ok {
+		return format, nil
+	}
+
+	if format, ok := extensionFormats[path.Ext(url.Path)]; ok {
+		return format, nil
+	}
+
+	return "", fmt.Errorf("unsupported content type %q and no known extension in %q, use the format option to set the format", mt, url.Path)
+}
+
+func yamlDocuments(url *url.URL) datasources.YamlDocuments {
+	if documents := url.Query().Get("documents"); documents != "" {
+		return datasources.YamlDocuments(documents)
+	}
+	return datasources.YamlDocumentsSingle
+}
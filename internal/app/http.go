@@ -0,0 +1,225 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHttpTimeout = 30 * time.Second
+	defaultHttpBackoff = 500 * time.Millisecond
+)
+
+type httpOptions struct {
+	headers    http.Header
+	username   string
+	password   string
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+	caCert     string
+	clientCert string
+	clientKey  string
+}
+
+// parseHttpOptions parses the options of an HTTP datasource from the URL query.
+// Header values may reference environment variables ($VAR or ${VAR}), and bearer tokens and basic auth
+// credentials may be read from one with env:NAME.
+func parseHttpOptions(url *url.URL) (httpOptions, error) {
+	query := url.Query()
+	options := httpOptions{
+		headers:    http.Header{},
+		username:   credentialOption(query.Get("username")),
+		password:   credentialOption(query.Get("password")),
+		timeout:    defaultHttpTimeout,
+		backoff:    defaultHttpBackoff,
+		caCert:     query.Get("ca_cert"),
+		clientCert: query.Get("client_cert"),
+		clientKey:  query.Get("client_key"),
+	}
+
+	for _, header := range query["header"] {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return options, fmt.Errorf("invalid header option %q: expected Name:Value", header)
+		}
+		options.headers.Add(strings.TrimSpace(name), os.ExpandEnv(strings.TrimSpace(value)))
+	}
+
+	if bearer := query.Get("bearer"); bearer != "" {
+		options.headers.Set("Authorization", "Bearer "+credentialOption(bearer))
+	}
+
+	if (options.clientCert == "") != (options.clientKey == "") {
+		return options, errors.New("client_cert and client_key options must be used together")
+	}
+
+	var err error
+	if timeout := query.Get("timeout"); timeout != "" {
+		if options.timeout, err = time.ParseDuration(timeout); err != nil {
+			return options, fmt.Errorf("invalid timeout option %q: %s", timeout, err)
+		}
+	}
+	if backoff := query.Get("backoff"); backoff != "" {
+		if options.backoff, err = time.ParseDuration(backoff); err != nil {
+			return options, fmt.Errorf("invalid backoff option %q: %s", backoff, err)
+		}
+	}
+	if retries := query.Get("retries"); retries != "" {
+		if options.retries, err = strconv.Atoi(retries); err != nil || options.retries < 0 {
+			return options, fmt.Errorf("invalid retries option %q: must be a non-negative integer", retries)
+		}
+	}
+
+	return options, nil
+}
+
+// credentialOption returns the value of the environment variable for env:NAME, and any other value as is
+func credentialOption(value string) string {
+	if name, ok := strings.CutPrefix(value, "env:"); ok {
+		return os.Getenv(name)
+	}
+	return value
+}
+
+func newHttpClient(options httpOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{}
+
+	if options.caCert != "" {
+		pem, err := os.ReadFile(options.caCert)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", options.caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if options.clientCert != "" {
+		cert, err := tls.LoadX509KeyPair(options.clientCert, options.clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   options.timeout,
+	}, nil
+}
+
+// fetchHttp requests the URL, retrying with exponential backoff on network errors, 429 and 5xx responses.
+// Any other non-2xx response is returned as an error. If a cache is given, fresh cached responses are
+// served without a request, stale ones are revalidated, and the cache may be used when the server cannot be reached.
+func fetchHttp(url *url.URL, cache *httpCache) (*http.Response, error) {
+	options, err := parseHttpOptions(url)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newHttpClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	requestUrl := stripDatasourceOptions(url).String()
+	if cache == nil {
+		return doHttpRequest(client, options, requestUrl, nil)
+	}
+
+	key := httpCacheKey(requestUrl, options)
+	entry := cache.load(key)
+	if entry != nil && entry.fresh(cache.ttl) {
+		return entry.response(), nil
+	}
+
+	res, err := doHttpRequest(client, options, requestUrl, entry)
+	if err != nil {
+		if entry != nil && cache.offlineFallback && isUnreachable(err) {
+			log.Printf("Using cached response for %s: %s", requestUrl, err)
+			return entry.response(), nil
+		}
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified {
+		_ = res.Body.Close()
+		if etag := res.Header.Get("ETag"); etag != "" {
+			entry.ETag = etag
+		}
+		if entry.refresh(res.Header) {
+			if err := cache.save(entry); err != nil {
+				return nil, err
+			}
+		}
+		return entry.response(), nil
+	}
+
+	return cache.store(key, res)
+}
+
+// isUnreachable reports whether the request failed because the server could not be reached, as opposed to
+// a response with an error status or a TLS failure, which must not be hidden by a cached response
+func isUnreachable(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// doHttpRequest sends the request with retries. If a cache entry is given, the request is made conditional
+// and a 304 response is returned as is.
+func doHttpRequest(client *http.Client, options httpOptions, requestUrl string, entry *httpCacheEntry) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			time.Sleep(options.backoff * time.Duration(1<<(attempt-1)))
+		}
+
+		req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header = options.headers.Clone()
+		if options.username != "" || options.password != "" {
+			req.SetBasicAuth(options.username, options.password)
+		}
+		if entry != nil {
+			entry.setConditionalHeaders(req)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			if attempt < options.retries {
+				continue
+			}
+			return nil, err
+		}
+
+		if (res.StatusCode >= 200 && res.StatusCode < 300) || (entry != nil && res.StatusCode == http.StatusNotModified) {
+			return res, nil
+		}
+		_ = res.Body.Close()
+
+		retryable := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+		if !retryable || attempt >= options.retries {
+			return nil, fmt.Errorf("unexpected status: %s", res.Status)
+		}
+	}
+}
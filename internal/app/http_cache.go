@@ -0,0 +1,186 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpCache stores HTTP datasource responses on disk, keyed by URL and request credentials
+type httpCache struct {
+	dir             string
+	ttl             time.Duration // Freshness lifetime of responses without a Cache-Control max-age
+	offlineFallback bool          // Serve the last cached response when the server is unreachable
+}
+
+type httpCacheEntry struct {
+	Key          string    `json:"key"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+	MaxAge       *int64    `json:"max_age,omitempty"` // In seconds, from the Cache-Control header
+	NoCache      bool      `json:"no_cache,omitempty"`
+
+	body []byte
+}
+
+func newHttpCache(dir string, ttl time.Duration, offlineFallback bool) (*httpCache, error) {
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("get user cache directory: %s", err)
+		}
+		dir = filepath.Join(userCacheDir, "renderkit", "http")
+	}
+	return &httpCache{dir, ttl, offlineFallback}, nil
+}
+
+// httpCacheKey identifies a request by its URL and a digest of its headers and basic auth credentials,
+// so that a response is never served to a request made with other credentials
+func httpCacheKey(requestUrl string, options httpOptions) string {
+	if len(options.headers) == 0 && options.username == "" && options.password == "" {
+		return requestUrl
+	}
+	credentials, _ := json.Marshal(struct {
+		Headers  http.Header
+		Username string
+		Password string
+	}{options.headers, options.username, options.password})
+	sum := sha256.Sum256(credentials)
+	return requestUrl + " " + hex.EncodeToString(sum[:])
+}
+
+func (c *httpCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// load returns the cached entry for the key, or nil if there is none
+func (c *httpCache) load(key string) *httpCacheEntry {
+	path := c.path(key)
+	meta, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil
+	}
+	entry := &httpCacheEntry{}
+	if err := json.Unmarshal(meta, entry); err != nil || entry.Key != key {
+		return nil
+	}
+	if entry.body, err = os.ReadFile(path + ".body"); err != nil {
+		return nil
+	}
+	return entry
+}
+
+// store caches the response unless its Cache-Control header forbids it.
+// The response's body is consumed and a response reading from the cached body is returned.
+func (c *httpCache) store(key string, res *http.Response) (*http.Response, error) {
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &httpCacheEntry{
+		Key:          key,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		ContentType:  res.Header.Get("Content-Type"),
+		body:         body,
+	}
+	if !entry.refresh(res.Header) {
+		return entry.response(), nil
+	}
+
+	if err := c.save(entry); err != nil {
+		return nil, err
+	}
+	return entry.response(), nil
+}
+
+func (c *httpCache) save(entry *httpCacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("create cache directory %s: %s", c.dir, err)
+	}
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	path := c.path(entry.Key)
+	if err := os.WriteFile(path+".body", entry.body, 0o600); err != nil {
+		return fmt.Errorf("write cache entry: %s", err)
+	}
+	if err := os.WriteFile(path+".json", meta, 0o600); err != nil {
+		return fmt.Errorf("write cache entry: %s", err)
+	}
+	return nil
+}
+
+// refresh updates the entry's freshness from the response headers.
+// It returns false if the response must not be stored.
+func (e *httpCacheEntry) refresh(header http.Header) bool {
+	e.StoredAt = time.Now()
+	e.MaxAge = nil
+	e.NoCache = false
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			return false
+		case "no-cache":
+			e.NoCache = true
+		case "max-age":
+			if seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil {
+				e.MaxAge = &seconds
+			}
+		}
+	}
+	return true
+}
+
+// fresh reports whether the entry can be used without revalidating it with the server
+func (e *httpCacheEntry) fresh(ttl time.Duration) bool {
+	if e.NoCache {
+		return false
+	}
+	lifetime := ttl
+	if e.MaxAge != nil {
+		lifetime = time.Duration(*e.MaxAge) * time.Second
+	}
+	return time.Since(e.StoredAt) < lifetime
+}
+
+// setConditionalHeaders adds the headers used to revalidate the entry with the server
+func (e *httpCacheEntry) setConditionalHeaders(req *http.Request) {
+	if e.ETag != "" {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
+
+func (e *httpCacheEntry) response() *http.Response {
+	header := http.Header{}
+	if e.ContentType != "" {
+		header.Set("Content-Type", e.ContentType)
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(e.body)),
+	}
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func fetchHttpBody(t *testing.T, rawUrl string, cache *httpCache) string {
+	url, err := url.Parse(rawUrl)
+	require.NoError(t, err)
+	res, err := fetchHttp(url, cache)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestFetchHttpCacheRevalidation(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprint(w, `{"key1": "value1"}`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	cache, err := newHttpCache(t.TempDir(), 0, false)
+	require.NoError(t, err)
+
+	require.Equal(t, `{"key1": "value1"}`, fetchHttpBody(t, ts.URL, cache))
+	require.Equal(t, `{"key1": "value1"}`, fetchHttpBody(t, ts.URL, cache))
+	require.Equal(t, 2, requests)
+
+	entry := cache.load(ts.URL)
+	require.NotNil(t, entry)
+	require.Equal(t, "application/json", entry.ContentType)
+}
+
+func TestFetchHttpCacheFreshness(t *testing.T) {
+	requests := 0
+	cacheControl := "max-age=60"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", cacheControl)
+		_, err := fmt.Fprintf(w, "request %d", requests)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	// Responses are served from the cache while they are fresh according to max-age
+	cache, err := newHttpCache(t.TempDir(), 0, false)
+	require.NoError(t, err)
+	require.Equal(t, "request 1", fetchHttpBody(t, ts.URL, cache))
+	require.Equal(t, "request 1", fetchHttpBody(t, ts.URL, cache))
+
+	// Or according to the TTL when there is no max-age
+	cacheControl = ""
+	cache, err = newHttpCache(t.TempDir(), time.Hour, false)
+	require.NoError(t, err)
+	require.Equal(t, "request 2", fetchHttpBody(t, ts.URL, cache))
+	require.Equal(t, "request 2", fetchHttpBody(t, ts.URL, cache))
+
+	// Responses with no-store are never cached
+	cacheControl = "no-store"
+	cache, err = newHttpCache(t.TempDir(), time.Hour, false)
+	require.NoError(t, err)
+	require.Equal(t, "request 3", fetchHttpBody(t, ts.URL, cache))
+	require.Equal(t, "request 4", fetchHttpBody(t, ts.URL, cache))
+}
+
+func TestFetchHttpCacheCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintf(w, "authorization %q", r.Header.Get("Authorization"))
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	// Fresh responses are only served to requests made with the same credentials
+	cache, err := newHttpCache(t.TempDir(), time.Hour, false)
+	require.NoError(t, err)
+	require.Equal(t, `authorization "Bearer a"`, fetchHttpBody(t, ts.URL+"?bearer=a", cache))
+	require.Equal(t, `authorization "Bearer b"`, fetchHttpBody(t, ts.URL+"?bearer=b", cache))
+	require.Equal(t, `authorization ""`, fetchHttpBody(t, ts.URL, cache))
+	require.Equal(t, `authorization "Basic dTpw"`, fetchHttpBody(t, ts.URL+"?username=u&password=p", cache))
+	require.Equal(t, `authorization "Bearer a"`, fetchHttpBody(t, ts.URL+"?bearer=a", cache))
+
+	// Credentials are not written to the cache entries' metadata
+	paths, err := filepath.Glob(filepath.Join(cache.dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, paths, 4)
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NotContains(t, string(content), "Bearer")
+	}
+}
+
+func TestFetchHttpCacheOfflineFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, `{"key1": "value1"}`)
+		require.NoError(t, err)
+	}))
+
+	dir := t.TempDir()
+	cache, err := newHttpCache(dir, 0, true)
+	require.NoError(t, err)
+	require.Equal(t, `{"key1": "value1"}`, fetchHttpBody(t, ts.URL, cache))
+	ts.Close()
+
+	require.Equal(t, `{"key1": "value1"}`, fetchHttpBody(t, ts.URL, cache))
+
+	cache, err = newHttpCache(dir, 0, false)
+	require.NoError(t, err)
+	url, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	_, err = fetchHttp(url, cache)
+	require.Error(t, err)
+}
+
+func TestFetchHttpCacheOfflineFallbackOnlyWhenUnreachable(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, err := fmt.Fprint(w, `{"key1": "value1"}`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	cache, err := newHttpCache(t.TempDir(), 0, true)
+	require.NoError(t, err)
+	require.Equal(t, `{"key1": "value1"}`, fetchHttpBody(t, ts.URL, cache))
+
+	// Error statuses are returned even though the response is cached
+	url, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	for _, status = range []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound} {
+		_, err = fetchHttp(url, cache)
+		require.ErrorContains(t, err, "unexpected status")
+	}
+}
+
+func TestFetchHttpCacheOfflineFallbackTlsError(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, `{"key1": "value1"}`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	// A cached response of the URL does not hide that the server's certificate is not trusted
+	cache, err := newHttpCache(t.TempDir(), 0, true)
+	require.NoError(t, err)
+	_, err = cache.store(ts.URL, &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("cached"))})
+	require.NoError(t, err)
+
+	url, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	_, err = fetchHttp(url, cache)
+	require.ErrorContains(t, err, "certificate")
+}
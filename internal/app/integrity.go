@@ -0,0 +1,132 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// hasIntegrityOptions reports whether the URL asks for the fetched body to be verified
+func hasIntegrityOptions(url *url.URL) bool {
+	query := url.Query()
+	return query.Has("sha256") || query.Has("signature") || query.Has("public_key")
+}
+
+// verifyIntegrity verifies the body against the expected SHA-256 digest (sha256 option) and
+// the detached signature (signature option, a path or HTTP/S URL) signed by the public key (public_key option).
+func verifyIntegrity(url *url.URL, body []byte) error {
+	query := url.Query()
+	for _, option := range []string{"sha256", "signature", "public_key"} {
+		if query.Has(option) && query.Get(option) == "" {
+			return fmt.Errorf("%s option must not be empty", option)
+		}
+	}
+
+	if expected := query.Get("sha256"); expected != "" {
+		sum := sha256.Sum256(body)
+		actual := hex.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(strings.ToLower(expected)), []byte(actual)) != 1 {
+			return fmt.Errorf("sha256 mismatch: expected %s, got %s", expected, actual)
+		}
+	}
+
+	signatureLocation := query.Get("signature")
+	publicKeyPath := query.Get("public_key")
+	if signatureLocation == "" && publicKeyPath == "" {
+		return nil
+	}
+	if signatureLocation == "" || publicKeyPath == "" {
+		return errors.New("signature and public_key options must be used together")
+	}
+
+	publicKey, err := readPublicKey(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("read public key: %s", err)
+	}
+	signature, err := readSignature(signatureLocation)
+	if err != nil {
+		return fmt.Errorf("read signature: %s", err)
+	}
+	if err := verifySignature(publicKey, body, signature); err != nil {
+		return fmt.Errorf("verify signature: %s", err)
+	}
+
+	return nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// readSignature reads a raw or base64 encoded signature from a local path or an HTTP/S URL
+func readSignature(location string) ([]byte, error) {
+	var data []byte
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		signatureUrl, err := url.Parse(location)
+		if err != nil {
+			return nil, err
+		}
+		res, err := fetchHttp(signatureUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if data, err = io.ReadAll(res.Body); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(location); err != nil {
+			return nil, err
+		}
+	}
+
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err == nil {
+		return decoded, nil
+	}
+	return data, nil
+}
+
+func verifySignature(publicKey crypto.PublicKey, body []byte, signature []byte) error {
+	digest := sha256.Sum256(body)
+
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, body, signature) {
+			return errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const integrityBody = `{"key1": "value1"}`
+
+func writePublicKey(t *testing.T, dir string, publicKey any) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "key.pub")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), os.ModePerm)
+	require.NoError(t, err)
+	return path
+}
+
+func TestWebFileLoadSha256(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprint(w, integrityBody)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	a := &App{}
+	sum := sha256.Sum256([]byte(integrityBody))
+
+	url, err := url.Parse(ts.URL + "/ds.json?sha256=" + hex.EncodeToString(sum[:]))
+	require.NoError(t, err)
+	ds, rc, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"key1": "value1"}, data)
+
+	url, err = url.Parse(ts.URL + "/ds.json?sha256=" + hex.EncodeToString(make([]byte, sha256.Size)))
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorContains(t, err, "sha256 mismatch")
+
+	// An empty digest is not treated as unset
+	url, err = url.Parse(ts.URL + "/ds.json?sha256=")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorContains(t, err, "sha256 option must not be empty")
+}
+
+func TestWebFileLoadSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signature := ed25519.Sign(privateKey, []byte(integrityBody))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ds.json.sig":
+			_, err := fmt.Fprint(w, base64.StdEncoding.EncodeToString(signature))
+			require.NoError(t, err)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, err := fmt.Fprint(w, integrityBody)
+			require.NoError(t, err)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	publicKeyPath := writePublicKey(t, dir, publicKey)
+	signaturePath := filepath.Join(dir, "ds.json.sig")
+	err = os.WriteFile(signaturePath, signature, os.ModePerm)
+	require.NoError(t, err)
+
+	a := &App{}
+	for _, signatureLocation := range []string{signaturePath, ts.URL + "/ds.json.sig"} {
+		url, err := url.Parse(ts.URL + "/ds.json")
+		require.NoError(t, err)
+		query := url.Query()
+		query.Set("signature", signatureLocation)
+		query.Set("public_key", publicKeyPath)
+		url.RawQuery = query.Encode()
+
+		ds, rc, err := a.createDatasourceFromURL(url)
+		require.NoError(t, err)
+		data, err := ds.Load()
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"key1": "value1"}, data)
+		require.NoError(t, rc.Close())
+	}
+
+	// A signature from another key is rejected
+	otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherDir := t.TempDir()
+	url, err := url.Parse(fmt.Sprintf("%s/ds.json?signature=%s&public_key=%s", ts.URL, signaturePath, writePublicKey(t, otherDir, otherPublicKey)))
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorContains(t, err, "invalid signature")
+
+	url, err = url.Parse(fmt.Sprintf("%s/ds.json?signature=%s", ts.URL, signaturePath))
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorContains(t, err, "signature and public_key options must be used together")
+
+	// A public key without a signature is not skipped
+	url, err = url.Parse(fmt.Sprintf("%s/ds.json?public_key=%s", ts.URL, publicKeyPath))
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorContains(t, err, "signature and public_key options must be used together")
+
+	url, err = url.Parse(fmt.Sprintf("%s/ds.json?signature=&public_key=%s", ts.URL, publicKeyPath))
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorContains(t, err, "signature option must not be empty")
+}
+
+func TestIntegrityOptionsOnlyForHttp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.json")
+	require.NoError(t, os.WriteFile(path, []byte(integrityBody), os.ModePerm))
+	sum := sha256.Sum256([]byte(integrityBody))
+
+	a := &App{}
+	for _, rawUrl := range []string{
+		path + "?sha256=" + hex.EncodeToString(sum[:]),
+		"env://HOME?public_key=key.pem",
+		"k8s://" + path + "?signature=values.sig",
+	} {
+		url, err := url.Parse(rawUrl)
+		require.NoError(t, err)
+		_, _, err = a.createDatasourceFromURL(url)
+		require.ErrorIs(t, err, ErrIntegrityNotSupported)
+	}
+}
+
+func TestVerifySignatureEcdsa(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(integrityBody))
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+	require.NoError(t, err)
+
+	require.NoError(t, verifySignature(&privateKey.PublicKey, []byte(integrityBody), signature))
+	require.Error(t, verifySignature(&privateKey.PublicKey, []byte("tampered"), signature))
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// interpolator resolves ${key path} references in the string values of the merged data
+type interpolator struct {
+	data     map[string]any
+	resolved map[string]bool // Locations whose references are resolved
+	active   []string        // Locations being resolved, to detect cycles
+}
+
+// referenceCycleError is returned as is through the references that lead to the cycle
+type referenceCycleError struct {
+	locations []string
+}
+
+func (e *referenceCycleError) Error() string {
+	return "reference cycle: " + strings.Join(e.locations, " -> ")
+}
+
+// interpolateData replaces ${key path} references in string values with the referenced values, in place.
+// A string that is a single reference takes the referenced value as is, so maps, lists and numbers can be reused,
+// while references inside a longer string must be scalars. $${ is an escaped ${.
+func interpolateData(data map[string]any) error {
+	in := &interpolator{data: data, resolved: make(map[string]bool)}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if _, err := in.resolveAt(data, k, formatKeyPath("", k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAt resolves the references in the value at the key (string) or index (int) of the parent,
+// stores the result in place and returns it
+func (in *interpolator) resolveAt(parent any, key any, location string) (any, error) {
+	if in.resolved[location] {
+		return getChild(parent, key), nil
+	}
+	if i := slices.Index(in.active, location); i != -1 {
+		return nil, &referenceCycleError{locations: append(slices.Clone(in.active[i:]), location)}
+	}
+	in.active = append(in.active, location)
+	defer func() { in.active = in.active[:len(in.active)-1] }()
+
+	switch v := getChild(parent, key).(type) {
+	case string:
+		value, err := in.interpolateString(v, location)
+		if err != nil {
+			return nil, err
+		}
+		setChild(parent, key, value)
+		in.markResolved(value, location)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if _, err := in.resolveAt(v, k, formatKeyPath(location, k)); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i := range v {
+			if _, err := in.resolveAt(v, i, fmt.Sprintf("%s[%d]", location, i)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	in.resolved[location] = true
+	return getChild(parent, key), nil
+}
+
+// markResolved marks the locations under a value copied from a reference as resolved, since the referenced
+// value was resolved before it was copied and must not be interpolated again
+func (in *interpolator) markResolved(value any, location string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, e := range v {
+			childLocation := formatKeyPath(location, k)
+			in.resolved[childLocation] = true
+			in.markResolved(e, childLocation)
+		}
+	case []any:
+		for i, e := range v {
+			childLocation := fmt.Sprintf("%s[%d]", location, i)
+			in.resolved[childLocation] = true
+			in.markResolved(e, childLocation)
+		}
+	}
+}
+
+func (in *interpolator) interpolateString(s string, location string) (any, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	rest := s
+	for {
+		start := strings.Index(rest, "${")
+		if start == -1 {
+			sb.WriteString(rest)
+			break
+		}
+		// $${ is an escaped ${
+		if start > 0 && rest[start-1] == '$' {
+			sb.WriteString(rest[:start-1])
+			sb.WriteString("${")
+			rest = rest[start+2:]
+			continue
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("%s: unterminated reference in %q", location, s)
+		}
+		end += start
+		ref := strings.TrimSpace(rest[start+2 : end])
+
+		value, err := in.lookup(ref)
+		if cycleErr := (*referenceCycleError)(nil); errors.As(err, &cycleErr) {
+			return nil, cycleErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: unresolved reference ${%s}: %s", location, ref, err)
+		}
+		// A value that is a single reference keeps the type of the referenced value
+		if rest == s && start == 0 && end == len(s)-1 {
+			return copyValue(value), nil
+		}
+		str, err := interpolatedString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: cannot interpolate ${%s}: %s", location, ref, err)
+		}
+
+		sb.WriteString(rest[:start])
+		sb.WriteString(str)
+		rest = rest[end+1:]
+	}
+	return sb.String(), nil
+}
+
+// lookup returns the resolved value at the key path (db.host, services[0].name)
+func (in *interpolator) lookup(ref string) (any, error) {
+	if ref == "" {
+		return nil, errors.New("empty reference")
+	}
+	segments, err := parseSelector("." + ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 || segments[0].isIndex {
+		return nil, errors.New("must start with a key")
+	}
+
+	var parent any = in.data
+	location := ""
+	for i, segment := range segments {
+		var key any
+		switch {
+		case segment.iterate:
+			return nil, errors.New("iterating is not supported")
+		case segment.isIndex:
+			list, ok := parent.([]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s with [%d]", describeValue(parent), segment.index)
+			}
+			index := segment.index
+			if index < 0 {
+				index += len(list)
+			}
+			if index < 0 || index >= len(list) {
+				return nil, fmt.Errorf("index %d out of range for a list of %d elements", segment.index, len(list))
+			}
+			key = index
+			location = fmt.Sprintf("%s[%d]", location, index)
+		default:
+			m, ok := parent.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot select key %q from %s", segment.key, describeValue(parent))
+			}
+			if _, ok := m[segment.key]; !ok {
+				return nil, fmt.Errorf("key %q not found", segment.key)
+			}
+			key = segment.key
+			location = formatKeyPath(location, segment.key)
+		}
+
+		// The referenced value is resolved entirely, while values along the way only need to be resolved
+		// when they are strings, which may be references to the maps and lists to descend into
+		child := getChild(parent, key)
+		if _, ok := child.(string); ok || i == len(segments)-1 {
+			if child, err = in.resolveAt(parent, key, location); err != nil {
+				return nil, err
+			}
+		}
+		parent = child
+	}
+
+	return parent, nil
+}
+
+// interpolatedString formats a scalar value to be interpolated into a string
+func interpolatedString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int64, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("value is %s, not a string, number or boolean", describeValue(v))
+	}
+}
+
+// formatKeyPath appends a key to a dotted key path, quoting keys that cannot be written with a dot
+func formatKeyPath(keyPath string, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\"' ") {
+		return fmt.Sprintf("%s[%q]", keyPath, key)
+	}
+	if keyPath == "" {
+		return key
+	}
+	return keyPath + "." + key
+}
+
+func getChild(parent any, key any) any {
+	switch p := parent.(type) {
+	case map[string]any:
+		return p[key.(string)]
+	case []any:
+		return p[key.(int)]
+	}
+	return nil
+}
+
+func setChild(parent any, key any, value any) {
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key.(string)] = value
+	case []any:
+		p[key.(int)] = value
+	}
+}
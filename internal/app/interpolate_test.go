@@ -0,0 +1,86 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolateData(t *testing.T) {
+	data := map[string]any{
+		"domain": "example.com",
+		"port":   8080,
+		"secure": true,
+		"api": map[string]any{
+			"url":   "https://${domain}:${port}/api?secure=${secure}",
+			"hosts": "${hosts}",
+		},
+		"hosts":  []any{"a", "${ domain }"},
+		"first":  "${hosts[-1]}",
+		"nested": "${api.url}/v1",
+		"quoted": `${labels["app.kubernetes.io/name"]}`,
+		"labels": map[string]any{"app.kubernetes.io/name": "web"},
+		"escape": "$${domain} costs $$5",
+	}
+
+	require.NoError(t, interpolateData(data))
+	require.Equal(t, map[string]any{
+		"domain": "example.com",
+		"port":   8080,
+		"secure": true,
+		"api": map[string]any{
+			"url":   "https://example.com:8080/api?secure=true",
+			"hosts": []any{"a", "example.com"},
+		},
+		"hosts":  []any{"a", "example.com"},
+		"first":  "example.com",
+		"nested": "https://example.com:8080/api?secure=true/v1",
+		"quoted": "web",
+		"labels": map[string]any{"app.kubernetes.io/name": "web"},
+		"escape": "${domain} costs $$5",
+	}, data)
+}
+
+func TestInterpolateDataSiblingReference(t *testing.T) {
+	// Referencing a sibling does not resolve the map that contains the reference
+	data := map[string]any{"db": map[string]any{"host": "localhost", "url": "postgres://${db.host}"}}
+	require.NoError(t, interpolateData(data))
+	require.Equal(t, "postgres://localhost", data["db"].(map[string]any)["url"])
+
+	// A reference to a string that is a reference is followed to the map it resolves to
+	data = map[string]any{"defaults": map[string]any{"host": "h"}, "db": "${defaults}", "host": "${db.host}"}
+	require.NoError(t, interpolateData(data))
+	require.Equal(t, "h", data["host"])
+}
+
+func TestInterpolateDataCopiedValuesAreNotInterpolatedAgain(t *testing.T) {
+	data := map[string]any{
+		"defaults": map[string]any{"tpl": "$${x}", "list": []any{"$${y}"}},
+		"db":       "${defaults}",
+		"out":      "${db.tpl}",
+		"item":     "${db.list[0]}",
+	}
+	require.NoError(t, interpolateData(data))
+	require.Equal(t, map[string]any{"tpl": "${x}", "list": []any{"${y}"}}, data["db"])
+	require.Equal(t, "${x}", data["out"])
+	require.Equal(t, "${y}", data["item"])
+}
+
+func TestInterpolateDataErrors(t *testing.T) {
+	testCases := []struct {
+		data map[string]any
+		err  string
+	}{
+		{map[string]any{"url": "https://${domain}"}, `url: unresolved reference ${domain}: key "domain" not found`},
+		{map[string]any{"a": "${b.c}", "b": "x"}, `a: unresolved reference ${b.c}: cannot select key "c" from a string value`},
+		{map[string]any{"a": "x${b}", "b": map[string]any{}}, "a: cannot interpolate ${b}: value is a map, not a string, number or boolean"},
+		{map[string]any{"a": "${b", "b": "x"}, `a: unterminated reference in "${b"`},
+		{map[string]any{"a": "${}"}, "a: unresolved reference ${}: empty reference"},
+		{map[string]any{"a": "${b}", "b": "x${c}", "c": []any{"${a}"}}, "reference cycle: a -> b -> c -> c[0] -> a"},
+		{map[string]any{"a": "${a}"}, "reference cycle: a -> a"},
+	}
+
+	for _, tc := range testCases {
+		require.EqualError(t, interpolateData(tc.data), tc.err)
+	}
+}
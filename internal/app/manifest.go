@@ -0,0 +1,242 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestTypes are the types a key can be declared with in a values manifest
+var manifestTypes = []string{"string", "integer", "number", "boolean", "list", "map", "any"}
+
+// valuesManifest declares the keys that templates expect in their data (renderkit.values.yaml)
+type valuesManifest struct {
+	path   string
+	Values map[string]valueDeclaration `yaml:"values"`
+}
+
+// valueDeclaration declares a key of the data by its dotted key path
+type valueDeclaration struct {
+	Description string `yaml:"description"`
+	Type        string `yaml:"type"`
+	Required    bool   `yaml:"required"`
+	Default     any    `yaml:"default"`
+}
+
+func loadValuesManifest(path string) (*valuesManifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &valuesManifest{path: path}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(manifest); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse values manifest %s: %s", path, err)
+	}
+
+	for _, keyPath := range manifest.keyPaths() {
+		declaration := manifest.Values[keyPath]
+		if _, err := manifestKeys(keyPath); err != nil {
+			return nil, fmt.Errorf("values manifest %s: %s", path, err)
+		}
+		if declaration.Type != "" && !slices.Contains(manifestTypes, declaration.Type) {
+			return nil, fmt.Errorf("values manifest %s: %s: unsupported type %s, supported types: %s", path, keyPath, declaration.Type, strings.Join(manifestTypes, ", "))
+		}
+		if declaration.Required && declaration.Default != nil {
+			return nil, fmt.Errorf("values manifest %s: %s: a required key cannot have a default value", path, keyPath)
+		}
+		if declaration.Default != nil && !matchesManifestType(declaration.Default, declaration.Type) {
+			return nil, fmt.Errorf("values manifest %s: %s: default value is %s, not %s", path, keyPath, describeValue(declaration.Default), declaration.Type)
+		}
+	}
+
+	return manifest, nil
+}
+
+// keyPaths returns the declared key paths in sorted order
+func (m *valuesManifest) keyPaths() []string {
+	keyPaths := make([]string, 0, len(m.Values))
+	for k := range m.Values {
+		keyPaths = append(keyPaths, k)
+	}
+	slices.Sort(keyPaths)
+	return keyPaths
+}
+
+// applyDefaults sets the default values of declared keys that are missing from data, creating the maps
+// along their key paths. It returns the top-level keys of data that were set.
+func (m *valuesManifest) applyDefaults(data map[string]any) []string {
+	var applied []string
+declarations:
+	for _, keyPath := range m.keyPaths() {
+		declaration := m.Values[keyPath]
+		if declaration.Default == nil {
+			continue
+		}
+		keys, _ := manifestKeys(keyPath)
+
+		current := data
+		for i, key := range keys[:len(keys)-1] {
+			next, exists := current[key]
+			if !exists {
+				next = make(map[string]any)
+				current[key] = next
+				if i == 0 {
+					applied = append(applied, key)
+				}
+			}
+			nextMap, ok := next.(map[string]any)
+			if !ok {
+				continue declarations // Reported by checkValuesManifest when the key is required
+			}
+			current = nextMap
+		}
+		last := keys[len(keys)-1]
+		if _, exists := current[last]; !exists {
+			current[last] = copyValue(declaration.Default)
+			if len(keys) == 1 {
+				applied = append(applied, last)
+			}
+		}
+	}
+	return applied
+}
+
+// checkValuesManifest verifies that the required keys are present and that the declared keys have their type
+func (a *App) checkValuesManifest(m *valuesManifest, data map[string]any) error {
+	var problems []string
+	for _, keyPath := range m.keyPaths() {
+		declaration := m.Values[keyPath]
+		keys, _ := manifestKeys(keyPath)
+
+		value, exists := lookupKeys(data, keys)
+		var problem string
+		switch {
+		case !exists && declaration.Required:
+			problem = "required key is missing"
+		case exists && !matchesManifestType(value, declaration.Type):
+			problem = fmt.Sprintf("got %s, want %s", describeValue(value), declaration.Type)
+			if source := a.dataSource(keys[0]); source != "" {
+				problem += fmt.Sprintf(" (from %s)", source)
+			}
+		default:
+			continue
+		}
+		if declaration.Description != "" {
+			problem += ": " + declaration.Description
+		}
+		problems = append(problems, fmt.Sprintf("  - %s: %s", keyPath, problem))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("data does not match values manifest %s:\n%s", m.path, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// writeDocs writes the documentation of the declared keys
+func (m *valuesManifest) writeDocs(w io.Writer) error {
+	for _, keyPath := range m.keyPaths() {
+		declaration := m.Values[keyPath]
+
+		var attributes []string
+		if declaration.Type != "" {
+			attributes = append(attributes, declaration.Type)
+		}
+		if declaration.Required {
+			attributes = append(attributes, "required")
+		}
+		if declaration.Default != nil {
+			attributes = append(attributes, "default: "+formatExplainValue(declaration.Default))
+		}
+
+		line := keyPath
+		if len(attributes) > 0 {
+			line += " (" + strings.Join(attributes, ", ") + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		if declaration.Description != "" {
+			if _, err := fmt.Fprintf(w, "  %s\n", declaration.Description); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// manifestKeys splits a declared key path (db.port or labels["app.kubernetes.io/name"]) into its keys
+func manifestKeys(keyPath string) ([]string, error) {
+	segments, err := parseSelector("." + keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty key path %q", keyPath)
+	}
+	keys := make([]string, len(segments))
+	for i, segment := range segments {
+		if segment.isIndex || segment.iterate {
+			return nil, fmt.Errorf("invalid key path %q: only keys are supported", keyPath)
+		}
+		keys[i] = segment.key
+	}
+	return keys, nil
+}
+
+func lookupKeys(data map[string]any, keys []string) (any, bool) {
+	var current any = data
+	for _, key := range keys {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func matchesManifestType(v any, typ string) bool {
+	switch typ {
+	case "", "any":
+		return true
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "integer":
+		switch v := v.(type) {
+		case int, int64, uint64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case "number":
+		switch v.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "list":
+		_, ok := v.([]any)
+		return ok
+	case "map":
+		_, ok := v.(map[string]any)
+		return ok
+	}
+	return false
+}
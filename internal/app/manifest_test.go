@@ -0,0 +1,110 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testValuesManifest = `values:
+  domain:
+    description: Public domain of the service
+    type: string
+    required: true
+  db.port:
+    description: Database port
+    type: integer
+    default: 5432
+  api.url:
+    type: string
+    default: "https://${domain}/api"
+  replicas:
+    description: Number of replicas
+    type: integer
+`
+
+func writeValuesManifest(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "renderkit.values.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), os.ModePerm))
+	return path
+}
+
+func TestValuesManifestDefaults(t *testing.T) {
+	manifest, err := loadValuesManifest(writeValuesManifest(t, testValuesManifest))
+	require.NoError(t, err)
+
+	a := &App{valuesManifest: manifest, interpolate: true}
+	_, err = a.loadDatasources(nil, []string{"domain=example.com", "replicas=3"}, false)
+	require.Error(t, err) // --data values are strings
+	require.Contains(t, err.Error(), "replicas: got a string value, want integer (from --data): Number of replicas")
+
+	data, err := a.loadDatasources(nil, []string{"domain=example.com"}, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"domain": "example.com",
+		"db":     map[string]any{"port": 5432},
+		"api":    map[string]any{"url": "https://example.com/api"},
+	}, data)
+	require.Equal(t, "values manifest default", a.dataSource("db"))
+}
+
+func TestValuesManifestKeepsExistingValues(t *testing.T) {
+	manifest, err := loadValuesManifest(writeValuesManifest(t, testValuesManifest))
+	require.NoError(t, err)
+
+	data := map[string]any{"domain": "example.com", "db": map[string]any{"host": "localhost", "port": 6432}}
+	require.Equal(t, []string{"api"}, manifest.applyDefaults(data))
+	require.Equal(t, map[string]any{"host": "localhost", "port": 6432}, data["db"])
+	require.Equal(t, map[string]any{"url": "https://${domain}/api"}, data["api"])
+}
+
+func TestValuesManifestMissingRequired(t *testing.T) {
+	manifest, err := loadValuesManifest(writeValuesManifest(t, testValuesManifest))
+	require.NoError(t, err)
+
+	a := &App{valuesManifest: manifest}
+	_, err = a.loadDatasources(nil, nil, false)
+	require.EqualError(t, err, "data does not match values manifest "+manifest.path+":\n"+
+		"  - domain: required key is missing: Public domain of the service")
+}
+
+func TestLoadValuesManifestErrors(t *testing.T) {
+	testCases := []struct {
+		content string
+		err     string
+	}{
+		{"values:\n  a:\n    type: object\n", "a: unsupported type object"},
+		{"values:\n  a:\n    required: true\n    default: 1\n", "a: a required key cannot have a default value"},
+		{"values:\n  a:\n    type: integer\n    default: one\n", "a: default value is a string value, not integer"},
+		{"values:\n  a[0]:\n    type: string\n", `invalid key path "a[0]": only keys are supported`},
+		{"values:\n  a:\n    typ: string\n", "field typ not found"},
+	}
+
+	for _, tc := range testCases {
+		_, err := loadValuesManifest(writeValuesManifest(t, tc.content))
+		require.ErrorContains(t, err, tc.err)
+	}
+}
+
+func TestValuesManifestDocs(t *testing.T) {
+	path := writeValuesManifest(t, testValuesManifest)
+
+	var out bytes.Buffer
+	app := NewApp("test")
+	app.cliApp.Writer = &out
+	require.NoError(t, app.Run([]string{"", "--values-manifest", path, "data", "--docs"}))
+	require.Equal(t, `api.url (string, default: "https://${domain}/api")
+db.port (integer, default: 5432)
+  Database port
+domain (string, required)
+  Public domain of the service
+replicas (integer)
+  Number of replicas
+`, out.String())
+
+	err := app.Run([]string{"", "data", "--docs"})
+	require.ErrorContains(t, err, "--docs requires a values manifest")
+}
@@ -1,15 +1,18 @@
 package app
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goreleaser/fileglob"
 	"github.com/orellazri/renderkit/internal/datasources"
@@ -25,9 +28,18 @@ var enginesMap = map[string]engines.Engine{
 	"mustache":    &engines.MustacheEngine{},
 }
 
+var ErrExecNotAllowed = errors.New("exec datasources are disabled, use --allow-exec to enable them")
+var ErrIntegrityNotSupported = errors.New("the sha256, signature and public_key options are only supported for HTTP/S datasources")
+
 func (a *App) parseDatasourceUrls(datasources []string) ([]*url.URL, error) {
 	datasourceUrls := make([]*url.URL, len(datasources))
 	for i, ds := range datasources {
+		// Existing files are opened as written, since parsing their name as a URL would fail on a % that is not
+		// an escape, and would take what follows a ? or # as options or a selector
+		if info, err := os.Stat(ds); err == nil && !info.IsDir() {
+			datasourceUrls[i] = &url.URL{Path: ds}
+			continue
+		}
 		url, err := url.Parse(ds)
 		if err != nil {
 			return nil, fmt.Errorf("invalid url %s: %s", ds, err)
@@ -41,6 +53,7 @@ func (a *App) parseDatasourceUrls(datasources []string) ([]*url.URL, error) {
 func (a *App) loadDatasources(datasourceUrls []*url.URL, extraData []string, allowDuplicateKeys bool) (map[string]any, error) {
 	duplicateKeys := []string{} // We keep track of duplicate keys to return a more informative error message
 	data := make(map[string]any)
+	a.dataOrigins = make(map[string][]dataOrigin)
 
 	// Load extra data
 	for _, d := range extraData {
@@ -49,28 +62,36 @@ func (a *App) loadDatasources(datasourceUrls []*url.URL, extraData []string, all
 			duplicateKeys = append(duplicateKeys, kv[0])
 		}
 		data[kv[0]] = kv[1]
+		a.recordOrigin(kv[0], dataOrigin{source: "--data", value: kv[1]})
 	}
 
 	for _, url := range datasourceUrls {
 		ds, f, err := a.createDatasourceFromURL(url)
 		if err != nil {
-			return nil, fmt.Errorf("create datasource %q: %s", url, err)
+			return nil, fmt.Errorf("create datasource %q: %s", redactDatasourceUrl(url), err)
 		}
 		if f != nil {
 			defer f.Close()
 		}
 
-		dsData, err := ds.Load()
+		dsData, err := loadDatasource(ds, url.Query().Get("key"), datasourceSelector(url))
 		if err != nil {
-			return nil, fmt.Errorf("load datasource %q: %s", url, err)
+			return nil, fmt.Errorf("load datasource %q: %s", redactDatasourceUrl(url), err)
 		}
 
-		// Merge with data dictionary
-		for k, v := range dsData {
+		// Merge with data dictionary, in a stable order so that traces and errors are reproducible
+		keys := make([]string, 0, len(dsData))
+		for k := range dsData {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			v := dsData[k]
 			if _, ok := data[k]; ok && !allowDuplicateKeys {
 				duplicateKeys = append(duplicateKeys, k)
 			}
 			data[k] = v
+			a.recordOrigin(k, dataOrigin{source: redactDatasourceUrl(url), url: url, value: v})
 		}
 	}
 
@@ -78,64 +99,494 @@ func (a *App) loadDatasources(datasourceUrls []*url.URL, extraData []string, all
 		return nil, fmt.Errorf("duplicate keys found in datasources: %s", strings.Join(duplicateKeys, ", "))
 	}
 
+	// Defaults are set before references are resolved, so that they can use references as well
+	if a.valuesManifest != nil {
+		for _, key := range a.valuesManifest.applyDefaults(data) {
+			a.recordOrigin(key, dataOrigin{source: "values manifest default", value: data[key]})
+		}
+	}
+	if a.dataSchema != nil {
+		for _, key := range a.dataSchema.applyDefaults(data) {
+			a.recordOrigin(key, dataOrigin{source: "schema default", value: data[key]})
+		}
+	}
+	if a.interpolate {
+		if err := interpolateData(data); err != nil {
+			return nil, fmt.Errorf("interpolate data: %s", err)
+		}
+	}
+	if a.valuesManifest != nil {
+		if err := a.checkValuesManifest(a.valuesManifest, data); err != nil {
+			return nil, err
+		}
+	}
+
 	return data, nil
 }
 
+// loadDatasource loads the datasource's data, narrowed down by the selector and mounted under key if they are given.
+// Mounted datasources may provide data that is not a map, such as a list of records.
+func loadDatasource(ds datasources.Datasource, key string, selector string) (map[string]any, error) {
+	if key == "" && selector == "" {
+		return ds.Load()
+	}
+
+	var value any
+	var err error
+	if vds, ok := ds.(datasources.ValueDatasource); ok {
+		value, err = vds.LoadValue()
+	} else {
+		value, err = ds.Load()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if selector != "" {
+		return selectData(value, selector, key)
+	}
+	return map[string]any{key: value}, nil
+}
+
 func (a *App) createDatasourceFromURL(url *url.URL) (datasources.Datasource, io.ReadCloser, error) {
-	urlWithoutPrefix := strings.TrimPrefix(url.String(), fmt.Sprintf("%s://", url.Scheme))
+	// Other datasources are not verified, which must not go unnoticed
+	if hasIntegrityOptions(url) && url.Scheme != "http" && url.Scheme != "https" {
+		return nil, nil, ErrIntegrityNotSupported
+	}
 
 	switch url.Scheme {
 	case "":
-		f, err := os.Open(urlWithoutPrefix)
+		format := url.Query().Get("format")
+		if format == "" {
+			ext := filepath.Ext(url.Path)
+			var ok bool
+			if format, ok = extensionFormats[ext]; !ok {
+				return nil, nil, fmt.Errorf("unsupported file extension: %s", ext)
+			}
+		}
+		f, err := openDatasourceFile(url.Path, format)
 		if err != nil {
 			return nil, nil, err
 		}
-		switch filepath.Ext(urlWithoutPrefix) {
-		case ".yaml", ".yml":
-			return datasources.NewYamlDatasource(f), f, nil
-		case ".json":
-			return datasources.NewJsonDatasource(f), f, nil
-		case ".toml":
-			return datasources.NewTomlDatasource(f), f, nil
-		case ".env":
-			return datasources.NewEnvFileDatasource(f), f, nil
-		default:
-			return nil, nil, fmt.Errorf("unsupported file extension: %s", filepath.Ext(urlWithoutPrefix))
+		ds, err := newFormatDatasource(format, f, url.Path, url)
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, err
 		}
+		return ds, f, nil
 	case "env":
-		variable := ""
-		if url.Host != "" {
-			variable = urlWithoutPrefix
+		options, err := envOptions(url)
+		if err != nil {
+			return nil, nil, err
+		}
+		return datasources.NewEnvDatasourceWithOptions(url.Host, options), nil, nil
+	case "stdin":
+		format := url.Query().Get("format")
+		if format == "" {
+			format = "yaml"
+		}
+		ds, err := newFormatDatasource(format, a.stdinReader(), "stdin", url)
+		if err != nil {
+			return nil, nil, err
 		}
-		return datasources.NewEnvDatasource(variable), nil, nil
+		return ds, nil, nil
+	case "exec":
+		if !a.allowExec {
+			return nil, nil, ErrExecNotAllowed
+		}
+		ds, err := newExecDatasource(url)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ds, nil, nil
+	case "git", "git+file":
+		ds, err := newGitDatasource(url)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ds, nil, nil
+	case "vault":
+		ds, err := newVaultDatasource(url)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ds, nil, nil
+	case "consul":
+		ds, err := newConsulDatasource(url)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ds, nil, nil
+	case "etcd":
+		ds, err := newEtcdDatasource(url)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ds, nil, nil
+	case "tfstate":
+		includeSensitive, err := parseBoolOption(url, "sensitive")
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := os.Open(url.Host + url.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return datasources.NewTfstateDatasource(f, includeSensitive), f, nil
+	case "sqlite":
+		query := url.Query()
+		return datasources.NewSqliteDatasource(url.Host+url.Path, query.Get("query"), query.Get("key_column")), nil, nil
+	case "k8s":
+		f, err := openDatasourceFile(url.Host+url.Path, "yaml")
+		if err != nil {
+			return nil, nil, err
+		}
+		return datasources.NewK8sDatasource(f, url.Query().Get("namespace")), f, nil
 	case "http", "https":
-		res, err := http.Get(url.String())
+		res, err := fetchHttp(url, a.httpCache)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		ct := res.Header.Get("Content-Type")
-		mt, _, _ := mime.ParseMediaType(ct)
-
-		var targetDs datasources.Datasource
+		// Verify the whole body before it is handed to the datasource
+		if hasIntegrityOptions(url) {
+			body, err := io.ReadAll(res.Body)
+			_ = res.Body.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := verifyIntegrity(url, body); err != nil {
+				return nil, nil, err
+			}
+			res.Body = io.NopCloser(bytes.NewReader(body))
+		}
 
-		switch mt {
-		case "application/json":
-			targetDs = datasources.NewJsonDatasource(res.Body)
-		case "application/toml":
-			targetDs = datasources.NewTomlDatasource(res.Body)
-		case "application/yaml", "text/yaml", "text/x-yaml", "application/x-yaml":
-			targetDs = datasources.NewYamlDatasource(res.Body)
-		default:
-			return nil, nil, fmt.Errorf("unsupported content type: %s", mt)
+		format, err := httpFormat(url, res)
+		if err != nil {
+			_ = res.Body.Close()
+			return nil, nil, err
+		}
+		ds, err := newFormatDatasource(format, res.Body, redactDatasourceUrl(url), url)
+		if err != nil {
+			_ = res.Body.Close()
+			return nil, nil, err
 		}
 
-		return targetDs, res.Body, nil
+		return ds, res.Body, nil
 	default:
 		return nil, nil, fmt.Errorf("scheme not supported: %s", url.Scheme)
 	}
 }
 
+// envOptions parses the options of an env datasource (env://?prefix=APP_&strip=true&case=lower&nest=true&infer=true)
+// or of a single variable (env://VAR?default=value or env://VAR?optional=true)
+func envOptions(url *url.URL) (datasources.EnvOptions, error) {
+	query := url.Query()
+	options := datasources.EnvOptions{
+		Prefix:  query.Get("prefix"),
+		KeyCase: query.Get("case"),
+	}
+	if options.KeyCase != "" && options.KeyCase != "lower" && options.KeyCase != "upper" {
+		return options, fmt.Errorf("invalid case option %q: must be lower or upper", options.KeyCase)
+	}
+
+	var err error
+	if options.StripPrefix, err = parseBoolOption(url, "strip"); err != nil {
+		return options, err
+	}
+	if options.Nest, err = parseBoolOption(url, "nest"); err != nil {
+		return options, err
+	}
+	if options.InferTypes, err = parseBoolOption(url, "infer"); err != nil {
+		return options, err
+	}
+	if options.Optional, err = parseBoolOption(url, "optional"); err != nil {
+		return options, err
+	}
+	if query.Has("default") {
+		defaultValue := query.Get("default")
+		options.Default = &defaultValue
+	}
+	return options, nil
+}
+
+// newExecDatasource creates a datasource that runs the command in the URL (exec://command?arg=...) and parses its stdout
+func newExecDatasource(url *url.URL) (datasources.Datasource, error) {
+	query := url.Query()
+	command := url.Host + url.Path
+	if command == "" {
+		return nil, errors.New("command is required")
+	}
+
+	options := datasources.ExecOptions{
+		Args: query["arg"],
+		Dir:  query.Get("dir"),
+		Env:  query["env"],
+	}
+	if timeout := query.Get("timeout"); timeout != "" {
+		var err error
+		if options.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout option %q: %s", timeout, err)
+		}
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "yaml"
+	}
+	if _, err := newFormatDatasource(format, nil, command, url); err != nil {
+		return nil, err
+	}
+
+	return datasources.NewExecDatasource(command, options, func(r io.Reader) (datasources.Datasource, error) {
+		return newFormatDatasource(format, r, command, url)
+	}), nil
+}
+
+// newGitDatasource creates a datasource that reads a file from a local git repository at a ref, defaulting to HEAD
+// (git:///path/to/repo.git//path/in/repo.yaml?ref=v1.0.0). SOPS encrypted files are decrypted in memory.
+func newGitDatasource(url *url.URL) (datasources.Datasource, error) {
+	repo, path, ok := strings.Cut(url.Host+url.Path, "//")
+	if !ok || repo == "" || path == "" {
+		return nil, errors.New("expected git:///<path to repository>//<path in repository>")
+	}
+
+	format := url.Query().Get("format")
+	if format == "" {
+		ext := filepath.Ext(path)
+		if format, ok = extensionFormats[ext]; !ok {
+			return nil, fmt.Errorf("unsupported file extension: %s", ext)
+		}
+	}
+	if _, err := newFormatDatasource(format, nil, path, url); err != nil {
+		return nil, err
+	}
+
+	return datasources.NewGitDatasource(repo, url.Query().Get("ref"), path, func(r io.Reader) (datasources.Datasource, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = decryptSopsIfEncrypted(format, data); err != nil {
+			return nil, err
+		}
+		return newFormatDatasource(format, bytes.NewReader(data), path, url)
+	}), nil
+}
+
+// newVaultDatasource creates a datasource that reads the secret in the URL (vault://mount/path#key).
+// Mounts with several path segments are set with the mount option (vault://team/kv/path?mount=team/kv).
+// The server address and credentials are read from the standard VAULT_* environment variables.
+func newVaultDatasource(url *url.URL) (datasources.Datasource, error) {
+	mount, path := url.Host, strings.Trim(url.Path, "/")
+	if url.Host == "" || path == "" {
+		return nil, errors.New("expected vault://<mount>/<path>[#key]")
+	}
+
+	query := url.Query()
+	if m := strings.Trim(query.Get("mount"), "/"); m != "" {
+		rest, ok := strings.CutPrefix(url.Host+"/"+path, m+"/")
+		if !ok || rest == "" {
+			return nil, fmt.Errorf("invalid mount option %q: must be followed by the secret path in the URL", m)
+		}
+		mount, path = m, rest
+	}
+	options := datasources.VaultOptions{
+		Address:      os.Getenv("VAULT_ADDR"),
+		Token:        os.Getenv("VAULT_TOKEN"),
+		RoleID:       os.Getenv("VAULT_ROLE_ID"),
+		SecretID:     os.Getenv("VAULT_SECRET_ID"),
+		Namespace:    os.Getenv("VAULT_NAMESPACE"),
+		AppRoleMount: query.Get("approle_mount"),
+	}
+	if address := query.Get("address"); address != "" {
+		options.Address = address
+	}
+	if kv := query.Get("kv"); kv != "" {
+		var err error
+		if options.KVVersion, err = strconv.Atoi(kv); err != nil || (options.KVVersion != 1 && options.KVVersion != 2) {
+			return nil, fmt.Errorf("invalid kv option %q: must be 1 or 2", kv)
+		}
+	}
+
+	httpOptions, err := parseHttpOptions(url)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newHttpClient(httpOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	key := url.Fragment
+	if isSelector(key) {
+		key = ""
+	}
+	return datasources.NewVaultDatasource(mount, path, key, options, client), nil
+}
+
+// openDatasourceFile opens a local datasource file. SOPS encrypted files are decrypted in memory.
+func openDatasourceFile(path string, format string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !sopsFormats[format] {
+		return f, nil
+	}
+
+	data, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if data, err = decryptSopsIfEncrypted(format, data); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// newConsulDatasource creates a datasource that reads the key, or the key prefix when it ends with a slash,
+// in the URL (consul://path/to/key). The agent address and token are read from CONSUL_HTTP_ADDR and CONSUL_HTTP_TOKEN.
+func newConsulDatasource(url *url.URL) (datasources.Datasource, error) {
+	key := strings.TrimPrefix(url.Host+url.Path, "/")
+	if key == "" {
+		return nil, errors.New("expected consul://<key>")
+	}
+
+	options := datasources.ConsulOptions{
+		Address: os.Getenv("CONSUL_HTTP_ADDR"),
+		Token:   os.Getenv("CONSUL_HTTP_TOKEN"),
+	}
+	if address := url.Query().Get("address"); address != "" {
+		options.Address = address
+	}
+	if options.Address == "" {
+		options.Address = "127.0.0.1:8500"
+	}
+	if !strings.Contains(options.Address, "://") {
+		if ssl, _ := strconv.ParseBool(os.Getenv("CONSUL_HTTP_SSL")); ssl {
+			options.Address = "https://" + options.Address
+		} else {
+			options.Address = "http://" + options.Address
+		}
+	}
+
+	client, err := newKVClient(url)
+	if err != nil {
+		return nil, err
+	}
+	return datasources.NewConsulDatasource(key, options, client), nil
+}
+
+// newEtcdDatasource creates a datasource that reads the key, or the key prefix when it ends with a slash,
+// in the URL (etcd://path/to/key, or etcd:///path/to/key for keys starting with a slash).
+// The endpoint and credentials are read from ETCDCTL_ENDPOINTS and ETCDCTL_USER.
+func newEtcdDatasource(url *url.URL) (datasources.Datasource, error) {
+	key := url.Host + url.Path
+	if key == "" || key == "/" {
+		return nil, errors.New("expected etcd://<key>")
+	}
+
+	var options datasources.EtcdOptions
+	if endpoints := os.Getenv("ETCDCTL_ENDPOINTS"); endpoints != "" {
+		options.Endpoint, _, _ = strings.Cut(endpoints, ",")
+	}
+	if user := os.Getenv("ETCDCTL_USER"); user != "" {
+		options.Username, options.Password, _ = strings.Cut(user, ":")
+	}
+	if address := url.Query().Get("address"); address != "" {
+		options.Endpoint = address
+	}
+	if options.Endpoint == "" {
+		options.Endpoint = "127.0.0.1:2379"
+	}
+	if !strings.Contains(options.Endpoint, "://") {
+		options.Endpoint = "http://" + options.Endpoint
+	}
+
+	client, err := newKVClient(url)
+	if err != nil {
+		return nil, err
+	}
+	return datasources.NewEtcdDatasource(key, options, client), nil
+}
+
+// newKVClient creates the HTTP client used to reach a key/value store, honoring the TLS and timeout HTTP options
+func newKVClient(url *url.URL) (*http.Client, error) {
+	httpOptions, err := parseHttpOptions(url)
+	if err != nil {
+		return nil, err
+	}
+	return newHttpClient(httpOptions)
+}
+
+// datasourceOptions are the query parameters interpreted by renderkit rather than passed on to remote datasources
+var datasourceOptions = []string{
+	"key", "select", "format", "documents", "nested",
+	"header", "bearer", "username", "password", "timeout", "retries", "backoff", "ca_cert", "client_cert", "client_key",
+	"sha256", "signature", "public_key",
+}
+
+// stripDatasourceOptions returns a copy of the URL without the query parameters interpreted by renderkit.
+// The other parameters are kept exactly as they were written, in their order, so that signed URLs still match.
+func stripDatasourceOptions(u *url.URL) *url.URL {
+	stripped := *u
+	var kept []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil && slices.Contains(datasourceOptions, unescaped) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	stripped.RawQuery = strings.Join(kept, "&")
+	return &stripped
+}
+
+// redactDatasourceUrl returns the URL to show in errors and traces, without the values of the password, bearer
+// and header options and of the password in the user info
+func redactDatasourceUrl(u *url.URL) string {
+	redacted := *u
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		switch unescaped, _ := url.QueryUnescape(name); unescaped {
+		case "password", "bearer":
+			params[i] = name + "=" + redactedValue
+		case "header":
+			// Keep the header name, which tells which header is set
+			params[i] = name + "=" + redactedValue
+			if header, err := url.QueryUnescape(value); err == nil {
+				if headerName, _, ok := strings.Cut(header, ":"); ok {
+					params[i] = name + "=" + url.QueryEscape(headerName) + ":" + redactedValue
+				}
+			}
+		}
+	}
+	redacted.RawQuery = strings.Join(params, "&")
+	return redacted.Redacted()
+}
+
+// parseBoolOption parses a boolean datasource option from the URL query, defaulting to false
+func parseBoolOption(url *url.URL, name string) (bool, error) {
+	v := url.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s option %q: %s", name, v, err)
+	}
+	return b, nil
+}
+
 func (a *App) compileGlob(pattern string) ([]string, error) {
 	if err := fileglob.ValidPattern(pattern); err != nil {
 		return nil, fmt.Errorf("invalid glob pattern: %q", err)
@@ -1,13 +1,16 @@
 package app
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/orellazri/renderkit/internal/datasources"
@@ -50,6 +53,424 @@ func TestCreateTomlDatasourceFromURL(t *testing.T) {
 	require.IsType(t, &datasources.TomlDatasource{}, ds)
 }
 
+func TestCreateXmlDatasourceFromURL(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	file, err := os.Create(filepath.Join(tmpDir, "ds.xml"))
+	require.NoError(t, err)
+	url, err := url.Parse(file.Name())
+	require.NoError(t, err)
+	ds, _, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	require.IsType(t, &datasources.XmlDatasource{}, ds)
+}
+
+func TestCreateHclDatasourceFromURL(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	for _, name := range []string{"ds.hcl", "ds.tfvars"} {
+		file, err := os.Create(filepath.Join(tmpDir, name))
+		require.NoError(t, err)
+		url, err := url.Parse(file.Name())
+		require.NoError(t, err)
+		ds, _, err := a.createDatasourceFromURL(url)
+		require.NoError(t, err)
+		require.IsType(t, &datasources.HclDatasource{}, ds)
+	}
+}
+
+func TestCreateJsonLinesDatasourceFromURL(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	for _, name := range []string{"ds.jsonl", "ds.ndjson"} {
+		file, err := os.Create(filepath.Join(tmpDir, name))
+		require.NoError(t, err)
+		url, err := url.Parse(file.Name())
+		require.NoError(t, err)
+		ds, _, err := a.createDatasourceFromURL(url)
+		require.NoError(t, err)
+		require.IsType(t, &datasources.JsonLinesDatasource{}, ds)
+	}
+}
+
+func TestCreateIniDatasourceFromURL(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	file, err := os.Create(filepath.Join(tmpDir, "ds.ini"))
+	require.NoError(t, err)
+	url, err := url.Parse(file.Name())
+	require.NoError(t, err)
+	ds, _, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	require.IsType(t, &datasources.IniDatasource{}, ds)
+}
+
+func TestCreatePropertiesDatasourceFromURL(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	propertiesPath := filepath.Join(tmpDir, "ds.properties")
+	err := os.WriteFile(propertiesPath, []byte("db.host=localhost"), os.ModePerm)
+	require.NoError(t, err)
+
+	url, err := url.Parse(propertiesPath + "?nested=true")
+	require.NoError(t, err)
+	ds, f, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	defer f.Close()
+	require.IsType(t, &datasources.PropertiesDatasource{}, ds)
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"db": map[string]any{"host": "localhost"}}, data)
+
+	url, err = url.Parse(propertiesPath + "?nested=maybe")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.Error(t, err)
+}
+
+func TestCreateEnvDatasourceFromURL(t *testing.T) {
+	t.Setenv("RENDERKIT_TEST_DB__HOST", "localhost")
+	t.Setenv("RENDERKIT_TEST_DB__PORT", "5432")
+
+	a := &App{}
+	url, err := url.Parse("env://?prefix=RENDERKIT_TEST_&strip=true&case=lower&nest=true&infer=true")
+	require.NoError(t, err)
+	ds, _, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	require.IsType(t, &datasources.EnvDatasource{}, ds)
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"db": map[string]any{"host": "localhost", "port": int64(5432)}}, data)
+
+	url, err = url.Parse("env://RENDERKIT_NON_EXISTING_ENV_VAR?default=fallback")
+	require.NoError(t, err)
+	ds, _, err = a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"RENDERKIT_NON_EXISTING_ENV_VAR": "fallback"}, data)
+
+	url, err = url.Parse("env://RENDERKIT_NON_EXISTING_ENV_VAR?optional=true")
+	require.NoError(t, err)
+	ds, _, err = a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	url, err = url.Parse("env://?case=title")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.Error(t, err)
+}
+
+func TestCreateStdinDatasourceFromURL(t *testing.T) {
+	a := &App{stdin: strings.NewReader(`{"key1": "value1"}`)}
+	url, err := url.Parse("stdin://?format=json")
+	require.NoError(t, err)
+	ds, rc, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	require.Nil(t, rc)
+	require.IsType(t, &datasources.JsonDatasource{}, ds)
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"key1": "value1"}, data)
+
+	// YAML is used when no format is given
+	a = &App{stdin: strings.NewReader("key1: value1")}
+	url, err = url.Parse("stdin://")
+	require.NoError(t, err)
+	ds, _, err = a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	require.IsType(t, &datasources.YamlDatasource{}, ds)
+
+	url, err = url.Parse("stdin://?format=nothing")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.Error(t, err)
+}
+
+func TestCreateExecDatasourceFromURL(t *testing.T) {
+	url, err := url.Parse(`exec://sh?arg=-c&arg=echo+"key1: $RENDERKIT_EXEC_VAR"&env=RENDERKIT_EXEC_VAR=value1&timeout=5s`)
+	require.NoError(t, err)
+
+	// Exec datasources are disabled by default
+	a := &App{}
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorIs(t, err, ErrExecNotAllowed)
+
+	a = &App{allowExec: true}
+	ds, rc, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	require.Nil(t, rc)
+	require.IsType(t, &datasources.ExecDatasource{}, ds)
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"key1": "value1"}, data)
+
+	// Mounted datasources may provide scalar values
+	datasourceUrls, err := a.parseDatasourceUrls([]string{"exec://sh?arg=-c&arg=echo+v1.2.0&key=version"})
+	require.NoError(t, err)
+	data, err = a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"version": "v1.2.0"}, data)
+
+	url, err = url.Parse("exec://sh?format=nothing")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.Error(t, err)
+
+	url, err = url.Parse("exec://sh?timeout=forever")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.Error(t, err)
+}
+
+func TestAllowExecIsNotReadFromConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("allow-exec: true\ndatasource:\n  - exec://sh?arg=-c&arg=echo+key1:+value1\n"), os.ModePerm))
+
+	var out strings.Builder
+	app := NewApp("test")
+	app.cliApp.Writer = &out
+	err := app.Run([]string{"", "--config", configPath, "data"})
+	require.ErrorContains(t, err, ErrExecNotAllowed.Error())
+
+	require.NoError(t, app.Run([]string{"", "--config", configPath, "--allow-exec", "data"}))
+	require.Equal(t, "key1: value1\n", out.String())
+}
+
+func TestCreateVaultDatasourceFromURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "root-token", r.Header.Get("X-Vault-Token"))
+		switch r.URL.Path {
+		case "/v1/secret/data/myapp/config":
+			_, err := fmt.Fprint(w, `{"data": {"data": {"username": "admin", "password": "hunter2"}}}`)
+			require.NoError(t, err)
+		case "/v1/team/kv/data/myapp/config":
+			_, err := fmt.Fprint(w, `{"data": {"data": {"token": "team-token"}}}`)
+			require.NoError(t, err)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("VAULT_ADDR", ts.URL)
+	t.Setenv("VAULT_TOKEN", "root-token")
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{
+		"vault://secret/myapp/config?kv=2#password",
+		"vault://secret/myapp/config?kv=2&key=db",
+		"vault://team/kv/myapp/config?kv=2&mount=team/kv",
+	})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"password": "hunter2",
+		"db":       map[string]any{"username": "admin", "password": "hunter2"},
+		"token":    "team-token",
+	}, data)
+
+	for _, rawUrl := range []string{
+		"vault://secret",
+		"vault://secret/myapp/config?kv=3",
+		"vault://team/kv/myapp/config?mount=other/kv",
+		"vault://team/kv?mount=team/kv",
+	} {
+		url, err := url.Parse(rawUrl)
+		require.NoError(t, err)
+		_, _, err = a.createDatasourceFromURL(url)
+		require.Error(t, err)
+	}
+}
+
+func TestCreateConsulDatasourceFromURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "consul-token", r.Header.Get("X-Consul-Token"))
+		require.Equal(t, "/v1/kv/config/app/", r.URL.Path)
+		_, err := fmt.Fprint(w, `[{"Key": "config/app/db/host", "Value": "bG9jYWxob3N0"}]`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+	t.Setenv("CONSUL_HTTP_ADDR", strings.TrimPrefix(ts.URL, "http://"))
+	t.Setenv("CONSUL_HTTP_TOKEN", "consul-token")
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{"consul://config/app/", "consul://config/app/?key=app&address=" + ts.URL})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"db":  map[string]any{"host": "localhost"},
+		"app": map[string]any{"db": map[string]any{"host": "localhost"}},
+	}, data)
+}
+
+func TestCreateEtcdDatasourceFromURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v3/kv/range", r.URL.Path)
+		_, err := fmt.Fprint(w, `{"kvs": [{"key": "L2NvbmZpZy9hcHAvbmFtZQ==", "value": "d2Vi"}]}`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+	t.Setenv("ETCDCTL_ENDPOINTS", ts.URL+",http://127.0.0.1:1")
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{"etcd:///config/app/"})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"name": "web"}, data)
+
+	url, err := url.Parse("etcd:///")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.Error(t, err)
+}
+
+func TestCreateTfstateDatasourceFromURL(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "terraform.tfstate")
+	err := os.WriteFile(statePath, []byte(`{"password": {"sensitive": true, "value": "hunter2"}}`), os.ModePerm)
+	require.NoError(t, err)
+
+	url, err := url.Parse("tfstate://" + statePath)
+	require.NoError(t, err)
+	ds, f, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	defer f.Close()
+	require.IsType(t, &datasources.TfstateDatasource{}, ds)
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"outputs": map[string]any{}}, data)
+
+	url, err = url.Parse("tfstate://" + statePath + "?sensitive=true")
+	require.NoError(t, err)
+	ds, f, err = a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"outputs": map[string]any{"password": "hunter2"}}, data)
+}
+
+func TestCreateGitDatasourceFromURL(t *testing.T) {
+	repo := t.TempDir()
+	cmd := exec.Command("sh", "-c", `git init -q && printf 'version: 1\n' > values.yaml && git add values.yaml &&
+		git commit -q -m v1 && git tag v1 && printf 'version: 2\n' > values.yaml && git commit -q -am v2`)
+	cmd.Dir = repo
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{
+		"git://" + repo + "//values.yaml",
+		"git+file://" + repo + "//values.yaml?ref=v1&key=v1",
+	})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"version": 2, "v1": map[string]any{"version": 1}}, data)
+
+	for _, rawUrl := range []string{"git://" + repo, "git://" + repo + "//values.unknown"} {
+		url, err := url.Parse(rawUrl)
+		require.NoError(t, err)
+		_, _, err = a.createDatasourceFromURL(url)
+		require.Error(t, err)
+	}
+}
+
+func TestCreateSqliteDatasourceFromURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE hosts (name TEXT, ip TEXT); INSERT INTO hosts VALUES ('web', '10.0.0.1'), ('db', '10.0.0.2');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	a := &App{}
+	query := url.QueryEscape("SELECT name, ip FROM hosts ORDER BY name")
+	datasourceUrls, err := a.parseDatasourceUrls([]string{
+		"sqlite://" + path + "?key=hosts&query=" + query,
+		"sqlite://" + path + "?key=by_name&key_column=name&query=" + query,
+	})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"hosts": []any{
+			map[string]any{"name": "db", "ip": "10.0.0.2"},
+			map[string]any{"name": "web", "ip": "10.0.0.1"},
+		},
+		"by_name": map[string]any{
+			"db":  map[string]any{"name": "db", "ip": "10.0.0.2"},
+			"web": map[string]any{"name": "web", "ip": "10.0.0.1"},
+		},
+	}, data)
+}
+
+func TestCreateK8sDatasourceFromURL(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	manifestsPath := filepath.Join(tmpDir, "manifests.yaml")
+	err := os.WriteFile(manifestsPath, []byte(`
+kind: Secret
+metadata:
+  name: db
+  namespace: prod
+data:
+  password: aHVudGVyMg==
+---
+kind: ConfigMap
+metadata:
+  name: app
+  namespace: dev
+data:
+  LOG_LEVEL: debug
+`), os.ModePerm)
+	require.NoError(t, err)
+
+	datasourceUrls, err := a.parseDatasourceUrls([]string{"k8s://" + manifestsPath, "k8s://" + manifestsPath + "?namespace=prod&key=prod"})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"configmaps": map[string]any{"app": map[string]any{"LOG_LEVEL": "debug"}},
+		"secrets":    map[string]any{"db": map[string]any{"password": "hunter2"}},
+		"prod": map[string]any{
+			"configmaps": map[string]any{},
+			"secrets":    map[string]any{"db": map[string]any{"password": "hunter2"}},
+		},
+	}, data)
+}
+
+func TestWebXmlFileLoad(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		_, err := fmt.Fprint(w, "<config><key1>value1</key1></config>")
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	a := &App{}
+	url, err := url.Parse(ts.URL + "/ds.xml")
+	require.NoError(t, err)
+
+	ds, rc, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	defer rc.Close()
+	require.IsType(t, &datasources.XmlDatasource{}, ds)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"config": map[string]any{"key1": "value1"}}, data)
+}
+
 func TestWebFileLoad(t *testing.T) {
 	var err error
 	dsFiles := []string{"ds.json", "ds.toml", "ds.yaml"}
@@ -102,6 +523,95 @@ func TestWebFileLoad(t *testing.T) {
 	}
 }
 
+func TestWebFileLoadStripsDatasourceOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "env=prod", r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, err := fmt.Fprint(w, "{\"key1\": \"value1\"}\n")
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{ts.URL + "/ds?env=prod&key=records"})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"records": []any{map[string]any{"key1": "value1"}}}, data)
+}
+
+func TestStripDatasourceOptions(t *testing.T) {
+	u, err := url.Parse("https://example.com/ds.json?z=1&key=records&a=%2Fb&header=X-Api-Key:k&X-Amz-Signature=abc%3D&sha256=&b#.items")
+	require.NoError(t, err)
+	require.Equal(t, "z=1&a=%2Fb&X-Amz-Signature=abc%3D&b", stripDatasourceOptions(u).RawQuery)
+
+	u, err = url.Parse("https://example.com/ds.json?format=json&timeout=5s")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/ds.json", stripDatasourceOptions(u).String())
+}
+
+func TestWebFileLoadFormatFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ds.yaml":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, err := fmt.Fprint(w, "key1: value1")
+			require.NoError(t, err)
+		case "/ds.env":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, err := fmt.Fprint(w, "key1=value1")
+			require.NoError(t, err)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, err := fmt.Fprint(w, `{"key1": "value1"}`)
+			require.NoError(t, err)
+		}
+	}))
+	defer ts.Close()
+
+	a := &App{}
+	expectedData := map[string]any{"key1": "value1"}
+
+	for _, path := range []string{"/ds.yaml", "/ds.env", "/raw/ds?format=json"} {
+		url, err := url.Parse(ts.URL + path)
+		require.NoError(t, err)
+		ds, rc, err := a.createDatasourceFromURL(url)
+		require.NoError(t, err, path)
+		data, err := ds.Load()
+		require.NoError(t, err, path)
+		require.Equal(t, expectedData, data, path)
+		require.NoError(t, rc.Close())
+	}
+
+	url, err := url.Parse(ts.URL + "/raw/ds")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.ErrorContains(t, err, "unsupported content type")
+}
+
+func TestCreateDatasourceFromURLWithFormat(t *testing.T) {
+	a := &App{}
+	tmpDir := t.TempDir()
+	valuesPath := filepath.Join(tmpDir, "values.txt")
+	err := os.WriteFile(valuesPath, []byte("key1 = \"value1\""), os.ModePerm)
+	require.NoError(t, err)
+
+	url, err := url.Parse(valuesPath + "?format=toml")
+	require.NoError(t, err)
+	ds, f, err := a.createDatasourceFromURL(url)
+	require.NoError(t, err)
+	defer f.Close()
+	require.IsType(t, &datasources.TomlDatasource{}, ds)
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"key1": "value1"}, data)
+
+	url, err = url.Parse(valuesPath + "?format=nothing")
+	require.NoError(t, err)
+	_, _, err = a.createDatasourceFromURL(url)
+	require.Error(t, err)
+}
+
 func TestCreateInvalidDatasourceFromURL(t *testing.T) {
 	a := &App{}
 
@@ -132,6 +642,31 @@ func TestParseDatasourceUrls(t *testing.T) {
 	require.Equal(t, expectedUrls, urls)
 }
 
+func TestLoadDatasourcesWithSpecialCharactersInPath(t *testing.T) {
+	dir := t.TempDir()
+	percentPath := filepath.Join(dir, "100%.yaml")
+	require.NoError(t, os.WriteFile(percentPath, []byte("percent: true"), os.ModePerm))
+	hashPath := filepath.Join(dir, "values#prod.yaml")
+	require.NoError(t, os.WriteFile(hashPath, []byte("hash: true"), os.ModePerm))
+	plainPath := filepath.Join(dir, "values.yaml")
+	require.NoError(t, os.WriteFile(plainPath, []byte("plain: true"), os.ModePerm))
+
+	// As URLs, the % is an invalid escape and the # starts a selector
+	_, err := url.Parse(percentPath)
+	require.Error(t, err)
+	hashUrl, err := url.Parse(hashPath)
+	require.NoError(t, err)
+	require.Equal(t, "prod.yaml", hashUrl.Fragment)
+
+	// Existing files are opened as written, while options are parsed for other paths
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{percentPath, hashPath, plainPath + "?key=values"})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"percent": true, "hash": true, "values": map[string]any{"plain": true}}, data)
+}
+
 func TestLoadDatasources(t *testing.T) {
 	tmpDir := t.TempDir()
 	ds1File, err := os.Create(filepath.Join(tmpDir, "ds1.yaml"))
@@ -166,6 +701,76 @@ func TestLoadDatasources(t *testing.T) {
 	require.Equal(t, expectedData, data)
 }
 
+func TestLoadDatasourcesMountedUnderKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	yamlPath := filepath.Join(tmpDir, "ds.yaml")
+	err := os.WriteFile(yamlPath, []byte("key1: value1\n---\nkey1: value2"), os.ModePerm)
+	require.NoError(t, err)
+	jsonLinesPath := filepath.Join(tmpDir, "ds.jsonl")
+	err = os.WriteFile(jsonLinesPath, []byte("{\"name\": \"web\"}\n{\"name\": \"api\"}\n"), os.ModePerm)
+	require.NoError(t, err)
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{
+		yamlPath + "?documents=merge",
+		yamlPath + "?documents=list&key=docs",
+		jsonLinesPath + "?key=services",
+	})
+	require.NoError(t, err)
+	expectedData := map[string]any{
+		"key1": "value2",
+		"docs": []any{
+			map[string]any{"key1": "value1"},
+			map[string]any{"key1": "value2"},
+		},
+		"services": []any{
+			map[string]any{"name": "web"},
+			map[string]any{"name": "api"},
+		},
+	}
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	// List datasources must be mounted under a key
+	datasourceUrls, err = a.parseDatasourceUrls([]string{jsonLinesPath})
+	require.NoError(t, err)
+	_, err = a.loadDatasources(datasourceUrls, nil, false)
+	require.ErrorContains(t, err, datasources.ErrMountRequired.Error())
+}
+
+func TestLoadDatasourcesWithSelector(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonPath := filepath.Join(tmpDir, "data.json")
+	err := os.WriteFile(jsonPath, []byte(`{"services": [{"name": "web", "env": {"PORT": "8080"}}, {"name": "api", "env": {}}]}`), os.ModePerm)
+	require.NoError(t, err)
+	jsonLinesPath := filepath.Join(tmpDir, "ds.jsonl")
+	err = os.WriteFile(jsonLinesPath, []byte("{\"name\": \"web\"}\n{\"name\": \"api\"}\n"), os.ModePerm)
+	require.NoError(t, err)
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{
+		jsonPath + "#.services[0].env",
+		jsonPath + "?key=names#.services[].name",
+		jsonLinesPath + "?key=first&select=$[0].name",
+	})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"PORT":  "8080",
+		"names": []any{"web", "api"},
+		"first": "web",
+	}, data)
+
+	for _, ds := range []string{jsonPath + "#.services", jsonPath + "#.missing"} {
+		datasourceUrls, err = a.parseDatasourceUrls([]string{ds})
+		require.NoError(t, err)
+		_, err = a.loadDatasources(datasourceUrls, nil, false)
+		require.Error(t, err)
+	}
+}
+
 func TestCompileGlob(t *testing.T) {
 	app := &App{}
 
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/orellazri/renderkit/internal/datasources"
+	"gopkg.in/yaml.v3"
+)
+
+// dataOrigin is a value set for a top-level key of the merged data
+type dataOrigin struct {
+	source string   // Datasource URL, "--data" or "schema default"
+	url    *url.URL // Datasource URL, if the value was loaded from a datasource
+	value  any
+}
+
+// recordOrigin appends the origin to the history of the key, tracing the override if --trace-data is set
+func (a *App) recordOrigin(key string, origin dataOrigin) {
+	if a.dataOrigins == nil {
+		a.dataOrigins = make(map[string][]dataOrigin)
+	}
+	history := a.dataOrigins[key]
+	a.dataOrigins[key] = append(history, origin)
+
+	if !a.traceData {
+		return
+	}
+	path := []selectorSegment{{key: key}}
+	if len(history) == 0 {
+		_, _ = fmt.Fprintf(a.stderrWriter(), "trace: %s set by %s\n", key, describeOrigin(origin, path))
+		return
+	}
+	_, _ = fmt.Fprintf(a.stderrWriter(), "trace: %s set by %s, overriding %s\n", key, describeOrigin(origin, path), describeOrigin(history[len(history)-1], path))
+}
+
+// dataSource returns the source of the current value of a top-level key
+func (a *App) dataSource(key string) string {
+	history := a.dataOrigins[key]
+	if len(history) == 0 {
+		return ""
+	}
+	return history[len(history)-1].source
+}
+
+// explainData writes the value at the key path (db.host, .db.host or $.db["host"]), where it came from,
+// and every value it overrode, redacting the values of keys that match any of the redact patterns
+func (a *App) explainData(w io.Writer, data map[string]any, keyPath string, redactPatterns []string) error {
+	if err := checkRedactPatterns(redactPatterns); err != nil {
+		return err
+	}
+	selector := keyPath
+	if !isSelector(selector) {
+		selector = "." + selector
+	}
+	path, err := parseSelector(selector)
+	if err != nil {
+		return err
+	}
+	if len(path) == 0 || path[0].isIndex || path[0].iterate {
+		return fmt.Errorf("invalid key path %q: must start with a key", keyPath)
+	}
+	for _, segment := range path {
+		if segment.iterate {
+			return fmt.Errorf("invalid key path %q: iterating is not supported", keyPath)
+		}
+	}
+
+	value, err := applySelector(data, path)
+	if err != nil {
+		return fmt.Errorf("key %s: %s", keyPath, err)
+	}
+	history := a.dataOrigins[path[0].key]
+	if len(history) == 0 {
+		return fmt.Errorf("key %s: origin is unknown", keyPath)
+	}
+
+	_, _ = fmt.Fprintf(w, "%s = %s\n", keyPath, formatExplainValue(redactSelected(value, path, redactPatterns)))
+	_, _ = fmt.Fprintf(w, "  set by %s\n", describeOrigin(history[len(history)-1], path))
+
+	var overridden []string
+	for i := len(history) - 2; i >= 0; i-- {
+		previous, err := applySelector(history[i].value, path[1:])
+		if err != nil {
+			continue // The earlier value did not have the key path
+		}
+		overridden = append(overridden, fmt.Sprintf("    %s from %s", formatExplainValue(redactSelected(previous, path, redactPatterns)), describeOrigin(history[i], path)))
+	}
+	if len(overridden) > 0 {
+		_, _ = fmt.Fprintln(w, "  overrides:")
+		_, _ = fmt.Fprintln(w, strings.Join(overridden, "\n"))
+	}
+
+	return nil
+}
+
+func formatExplainValue(v any) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// describeOrigin returns the source of the origin, with the file and line that set the key path when they are known
+func describeOrigin(origin dataOrigin, path []selectorSegment) string {
+	file, line := originLocation(origin, path)
+	switch {
+	case file == "":
+		return origin.source
+	case line == 0 && file == origin.source:
+		return file
+	case line == 0:
+		return fmt.Sprintf("%s (%s)", origin.source, file)
+	case file == origin.source:
+		return fmt.Sprintf("%s:%d", file, line)
+	default:
+		return fmt.Sprintf("%s (%s:%d)", origin.source, file, line)
+	}
+}
+
+// originLocation returns the local file that set the key path, and its line for YAML, JSON and env files
+func originLocation(origin dataOrigin, path []selectorSegment) (string, int) {
+	if origin.url == nil || origin.url.Scheme != "" {
+		return "", 0
+	}
+	file := origin.url.Path
+
+	format := origin.url.Query().Get("format")
+	if format == "" {
+		format = extensionFormats[filepath.Ext(file)]
+	}
+	// Selected values are not at the key path in the file
+	if datasourceSelector(origin.url) != "" {
+		return file, 0
+	}
+	if key := origin.url.Query().Get("key"); key != "" {
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		return file, 0
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return file, 0
+	}
+	switch format {
+	case "yaml":
+		return file, yamlKeyLine(content, path, yamlDocuments(origin.url))
+	case "json":
+		return file, yamlKeyLine(content, path, datasources.YamlDocumentsFirst)
+	case "env":
+		if len(path) == 1 {
+			return file, envKeyLine(content, path[0].key)
+		}
+	}
+	return file, 0
+}
+
+// yamlKeyLine returns the line of the key path in YAML or JSON content, in the document that the key path
+// is loaded from: the first one by default, the last one that has the top-level key when documents are merged,
+// and the indexed one when they are loaded as a list
+func yamlKeyLine(content []byte, path []selectorSegment, documents datasources.YamlDocuments) int {
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			break
+		}
+		docs = append(docs, &doc)
+		if documents == datasources.YamlDocumentsSingle || documents == datasources.YamlDocumentsFirst {
+			break
+		}
+	}
+	if len(docs) == 0 {
+		return 0
+	}
+
+	switch documents {
+	case datasources.YamlDocumentsSingle, datasources.YamlDocumentsFirst:
+		return nodeLine(docs[0], path)
+	case datasources.YamlDocumentsMerge:
+		// Documents are merged at the top level, so the whole value of the key comes from the last one that has it
+		for i := len(docs) - 1; i >= 0; i-- {
+			if nodeLine(docs[i], path[:1]) > 0 {
+				return nodeLine(docs[i], path)
+			}
+		}
+	case datasources.YamlDocumentsList:
+		if !path[0].isIndex {
+			return 0
+		}
+		index := path[0].index
+		if index < 0 {
+			index += len(docs)
+		}
+		if index < 0 || index >= len(docs) {
+			return 0
+		}
+		if len(path) == 1 && len(docs[index].Content) > 0 {
+			return docs[index].Content[0].Line
+		}
+		return nodeLine(docs[index], path[1:])
+	}
+	return 0
+}
+
+func nodeLine(node *yaml.Node, path []selectorSegment) int {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := 0
+	for _, segment := range path {
+		switch {
+		case segment.isIndex && node.Kind == yaml.SequenceNode:
+			index := segment.index
+			if index < 0 {
+				index += len(node.Content)
+			}
+			if index < 0 || index >= len(node.Content) {
+				return 0
+			}
+			node = node.Content[index]
+			line = node.Line
+		case !segment.isIndex && node.Kind == yaml.MappingNode:
+			found := false
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment.key {
+					line = node.Content[i].Line
+					node = node.Content[i+1]
+					found = true
+				}
+			}
+			if !found {
+				return 0
+			}
+		default:
+			return 0
+		}
+	}
+	return line
+}
+
+// envKeyLine returns the line of the last assignment to the key in an env file
+func envKeyLine(content []byte, key string) int {
+	line := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "export ")
+		if name, _, ok := strings.Cut(text, "="); ok && strings.TrimSpace(name) == key {
+			line = n
+		}
+	}
+	return line
+}
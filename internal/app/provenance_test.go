@@ -0,0 +1,195 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/orellazri/renderkit/internal/datasources"
+	"github.com/stretchr/testify/require"
+)
+
+func writeProvenanceFiles(t *testing.T) (string, string, string) {
+	tmpDir := t.TempDir()
+	basePath := filepath.Join(tmpDir, "base.yaml")
+	require.NoError(t, os.WriteFile(basePath, []byte("name: app\ndb:\n  host: localhost\n  port: 5432\n"), os.ModePerm))
+	prodPath := filepath.Join(tmpDir, "prod.json")
+	require.NoError(t, os.WriteFile(prodPath, []byte("{\n  \"db\": {\n    \"host\": \"db.internal\"\n  }\n}\n"), os.ModePerm))
+	envPath := filepath.Join(tmpDir, "app.env")
+	require.NoError(t, os.WriteFile(envPath, []byte("# comment\nexport NAME=prod\n"), os.ModePerm))
+	return basePath, prodPath, envPath
+}
+
+func TestExplainData(t *testing.T) {
+	basePath, prodPath, envPath := writeProvenanceFiles(t)
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{basePath, prodPath, envPath + "?key=env"})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, []string{"name=cli"}, true)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, a.explainData(&out, data, "db.host", nil))
+	require.Equal(t, `db.host = "db.internal"
+  set by `+prodPath+`:3
+  overrides:
+    "localhost" from `+basePath+`:3
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, a.explainData(&out, data, "$.name", nil))
+	require.Equal(t, `$.name = "app"
+  set by `+basePath+`:1
+  overrides:
+    "cli" from --data
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, a.explainData(&out, data, `env["NAME"]`, nil))
+	require.Equal(t, `env["NAME"] = "prod"
+  set by `+envPath+`?key=env (`+envPath+`:2)
+`, out.String())
+
+	// The merge is shallow, so keys of earlier values are not kept
+	require.ErrorContains(t, a.explainData(&out, data, "db.port", nil), `key "port" not found`)
+	require.ErrorContains(t, a.explainData(&out, data, "[0]", nil), "must start with a key")
+}
+
+func TestExplainDataRedacted(t *testing.T) {
+	basePath, prodPath, _ := writeProvenanceFiles(t)
+
+	a := &App{}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{basePath, prodPath})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, true)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, a.explainData(&out, data, "db.host", []string{"host"}))
+	require.Equal(t, `db.host = "<redacted>"
+  set by `+prodPath+`:3
+  overrides:
+    "<redacted>" from `+basePath+`:3
+`, out.String())
+
+	// A matching parent key redacts the whole value
+	out.Reset()
+	require.NoError(t, a.explainData(&out, data, "db.host", []string{"db"}))
+	require.Contains(t, out.String(), `db.host = "<redacted>"`)
+	require.NotContains(t, out.String(), "localhost")
+
+	// Matching keys below the key path are redacted in the value
+	out.Reset()
+	require.NoError(t, a.explainData(&out, data, "db", []string{"db.host"}))
+	require.Equal(t, `db = {"host":"<redacted>"}
+  set by `+prodPath+`:2
+  overrides:
+    {"host":"<redacted>","port":5432} from `+basePath+`:2
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, a.explainData(&out, data, "name", []string{"db.*"}))
+	require.Contains(t, out.String(), `name = "app"`)
+
+	require.ErrorContains(t, a.explainData(&out, data, "db.host", []string{"["}), `invalid redact pattern "["`)
+}
+
+func TestTraceData(t *testing.T) {
+	basePath, prodPath, _ := writeProvenanceFiles(t)
+
+	var stderr bytes.Buffer
+	a := &App{stderr: &stderr, traceData: true}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{basePath, prodPath + "#.db"})
+	require.NoError(t, err)
+	_, err = a.loadDatasources(datasourceUrls, nil, true)
+	require.NoError(t, err)
+
+	require.Equal(t, "trace: db set by "+basePath+":2\n"+
+		"trace: name set by "+basePath+":1\n"+
+		"trace: host set by "+prodPath+"#.db ("+prodPath+")\n", stderr.String())
+}
+
+const multiDocumentYaml = `db:
+  host: first
+list:
+  - x: 1
+  - x: 2
+---
+db:
+  host: second
+---
+other: 1
+`
+
+func yamlKeyLineOf(t *testing.T, selector string, documents datasources.YamlDocuments) int {
+	path, err := parseSelector(selector)
+	require.NoError(t, err)
+	return yamlKeyLine([]byte(multiDocumentYaml), path, documents)
+}
+
+func TestYamlKeyLineFirstDocument(t *testing.T) {
+	require.Equal(t, 2, yamlKeyLineOf(t, ".db.host", datasources.YamlDocumentsFirst))
+	require.Equal(t, 5, yamlKeyLineOf(t, ".list[-1].x", datasources.YamlDocumentsFirst))
+	require.Equal(t, 0, yamlKeyLineOf(t, ".other", datasources.YamlDocumentsFirst))
+}
+
+func TestYamlKeyLineMergedDocuments(t *testing.T) {
+	require.Equal(t, 8, yamlKeyLineOf(t, ".db.host", datasources.YamlDocumentsMerge))
+	require.Equal(t, 3, yamlKeyLineOf(t, ".list", datasources.YamlDocumentsMerge))
+	require.Equal(t, 10, yamlKeyLineOf(t, ".other", datasources.YamlDocumentsMerge))
+	require.Equal(t, 0, yamlKeyLineOf(t, ".missing", datasources.YamlDocumentsMerge))
+}
+
+func TestYamlKeyLineDocumentList(t *testing.T) {
+	require.Equal(t, 2, yamlKeyLineOf(t, ".[0].db.host", datasources.YamlDocumentsList))
+	require.Equal(t, 8, yamlKeyLineOf(t, ".[1].db.host", datasources.YamlDocumentsList))
+	require.Equal(t, 10, yamlKeyLineOf(t, ".[-1]", datasources.YamlDocumentsList))
+	require.Equal(t, 0, yamlKeyLineOf(t, ".[3]", datasources.YamlDocumentsList))
+}
+
+func TestExplainDataDocuments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "multi.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(multiDocumentYaml), os.ModePerm))
+
+	for _, tc := range []struct {
+		query   string
+		keyPath string
+		output  string
+	}{
+		{"?documents=first", "db.host", `db.host = "first"` + "\n  set by " + path + "?documents=first (" + path + ":2)\n"},
+		{"?documents=merge", "db.host", `db.host = "second"` + "\n  set by " + path + "?documents=merge (" + path + ":8)\n"},
+		{"?documents=list&key=docs", "docs[1].db.host", `docs[1].db.host = "second"` + "\n  set by " + path + "?documents=list&key=docs (" + path + ":8)\n"},
+	} {
+		a := &App{}
+		datasourceUrls, err := a.parseDatasourceUrls([]string{path + tc.query})
+		require.NoError(t, err)
+		data, err := a.loadDatasources(datasourceUrls, nil, false)
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		require.NoError(t, a.explainData(&out, data, tc.keyPath, nil))
+		require.Equal(t, tc.output, out.String())
+	}
+}
+
+func TestDataExplainCommand(t *testing.T) {
+	basePath, prodPath, _ := writeProvenanceFiles(t)
+
+	var out bytes.Buffer
+	app := NewApp("test")
+	app.cliApp.Writer = &out
+	err := app.Run([]string{"", "--datasource", basePath, "--datasource", prodPath, "--allow-duplicate-keys", "data", "explain", "db.host"})
+	require.NoError(t, err)
+	require.Contains(t, out.String(), `db.host = "db.internal"`)
+
+	out.Reset()
+	err = app.Run([]string{"", "--datasource", basePath, "--datasource", prodPath, "--allow-duplicate-keys", "data", "--redact", "*host*", "explain", "db.host"})
+	require.NoError(t, err)
+	require.Contains(t, out.String(), `db.host = "<redacted>"`)
+	require.NotContains(t, out.String(), "localhost")
+
+	err = app.Run([]string{"", "--datasource", basePath, "data", "explain"})
+	require.ErrorContains(t, err, "expected exactly one key path")
+}
@@ -0,0 +1,233 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSchemaRefDepth limits how many $refs are followed in a row when applying defaults, to stop on cyclic references
+const maxSchemaRefDepth = 32
+
+// schemaViolation is a value of the data that does not match the schema
+type schemaViolation struct {
+	pointer string // JSON pointer to the value in the data
+	message string
+	source  string // Datasource that supplied the value, if known
+}
+
+func (v schemaViolation) String() string {
+	pointer := v.pointer
+	if pointer == "" {
+		pointer = "(root)"
+	}
+	if v.source == "" {
+		return fmt.Sprintf("%s: %s", pointer, v.message)
+	}
+	return fmt.Sprintf("%s: %s (from %s)", pointer, v.message, v.source)
+}
+
+// dataSchema is a JSON Schema (draft 2020-12, in JSON or YAML) that the merged data is validated against
+type dataSchema struct {
+	path   string
+	doc    map[string]any
+	schema *jsonschema.Schema
+}
+
+func loadDataSchema(path string) (*dataSchema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// YAML is a superset of JSON, so both are decoded the same way
+	var schemaDoc map[string]any
+	if err := yaml.Unmarshal(content, &schemaDoc); err != nil {
+		return nil, fmt.Errorf("parse schema %s: %s", path, err)
+	}
+
+	doc, err := toJsonValue(schemaDoc)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema %s: %s", path, err)
+	}
+	location, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	if err := compiler.AddResource(location, doc); err != nil {
+		return nil, fmt.Errorf("load schema %s: %s", path, err)
+	}
+	schema, err := compiler.Compile(location)
+	if err != nil {
+		return nil, fmt.Errorf("compile schema %s: %s", path, err)
+	}
+
+	return &dataSchema{path: path, doc: schemaDoc, schema: schema}, nil
+}
+
+// applyDefaults sets the default values of the schema that are missing from data, returning the top-level keys of
+// data that were set
+func (s *dataSchema) applyDefaults(data map[string]any) []string {
+	return applySchemaDefaults(s.doc, s.doc, data, 0)
+}
+
+// validateSchema validates the data against the schema and reports every violation with the datasource
+// that supplied the value. The schema's defaults are applied to the data when it is loaded.
+func (a *App) validateSchema(s *dataSchema, data map[string]any) error {
+	instance, err := toJsonValue(data)
+	if err != nil {
+		return fmt.Errorf("convert data: %s", err)
+	}
+	err = s.schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+
+	var violations []schemaViolation
+	a.collectViolations(validationErr, message.NewPrinter(language.English), &violations)
+	slices.SortStableFunc(violations, func(x, y schemaViolation) int {
+		return strings.Compare(x.pointer, y.pointer)
+	})
+
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = "  - " + v.String()
+	}
+	return fmt.Errorf("data does not match schema %s:\n%s", s.path, strings.Join(lines, "\n"))
+}
+
+// collectViolations flattens the validation error into the violations at its leaves
+func (a *App) collectViolations(err *jsonschema.ValidationError, printer *message.Printer, violations *[]schemaViolation) {
+	if len(err.Causes) == 0 {
+		var source string
+		if len(err.InstanceLocation) > 0 {
+			source = a.dataSource(err.InstanceLocation[0])
+		}
+		*violations = append(*violations, schemaViolation{
+			pointer: jsonPointer(err.InstanceLocation),
+			message: err.ErrorKind.LocalizedString(printer),
+			source:  source,
+		})
+		return
+	}
+	for _, cause := range err.Causes {
+		a.collectViolations(cause, printer, violations)
+	}
+}
+
+// applySchemaDefaults sets the default values of the schema's properties that are missing from data,
+// descending into nested objects and lists of objects, and following local $refs and allOf subschemas.
+// It returns the keys of data that were set.
+func applySchemaDefaults(root map[string]any, schema map[string]any, data map[string]any, refDepth int) []string {
+	var applied []string
+
+	if ref, ok := schema["$ref"].(string); ok && strings.HasPrefix(ref, "#") && refDepth < maxSchemaRefDepth {
+		if target, ok := resolveSchemaPointer(root, strings.TrimPrefix(ref, "#")).(map[string]any); ok {
+			applied = append(applied, applySchemaDefaults(root, target, data, refDepth+1)...)
+		}
+	}
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, s := range allOf {
+			if sub, ok := s.(map[string]any); ok {
+				applied = append(applied, applySchemaDefaults(root, sub, data, refDepth)...)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	for name, p := range properties {
+		property, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, exists := data[name]; !exists {
+			if def, ok := property["default"]; ok {
+				data[name] = copyValue(def)
+				applied = append(applied, name)
+			}
+		}
+
+		switch value := data[name].(type) {
+		case map[string]any:
+			applySchemaDefaults(root, property, value, 0)
+		case []any:
+			items, _ := property["items"].(map[string]any)
+			for _, item := range value {
+				if m, ok := item.(map[string]any); ok && items != nil {
+					applySchemaDefaults(root, items, m, 0)
+				}
+			}
+		}
+	}
+
+	return applied
+}
+
+// resolveSchemaPointer returns the value at the JSON pointer in the schema document
+func resolveSchemaPointer(root any, pointer string) any {
+	current := root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[token]
+	}
+	return current
+}
+
+func jsonPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString("/")
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// toJsonValue converts a Go value to the JSON representation expected by the validator
+func toJsonValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(b))
+}
+
+// copyValue returns a deep copy of maps and lists, so that defaults are never shared between values
+func copyValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []any:
+		l := make([]any, len(v))
+		for i, e := range v {
+			l[i] = copyValue(e)
+		}
+		return l
+	default:
+		return v
+	}
+}
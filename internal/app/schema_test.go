@@ -0,0 +1,117 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `
+$schema: https://json-schema.org/draft/2020-12/schema
+type: object
+required: [name, db]
+properties:
+  name:
+    type: string
+  replicas:
+    type: integer
+    default: 1
+  db:
+    $ref: "#/$defs/db"
+  services:
+    type: array
+    items:
+      type: object
+      properties:
+        port: {type: integer, default: 80}
+$defs:
+  db:
+    type: object
+    required: [host]
+    properties:
+      host: {type: string}
+      port: {type: integer, minimum: 1, default: 5432}
+`
+
+func writeSchemaFiles(t *testing.T, values string) (string, string) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.yaml")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(testSchema), os.ModePerm))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	require.NoError(t, os.WriteFile(valuesPath, []byte(values), os.ModePerm))
+	return schemaPath, valuesPath
+}
+
+func TestValidateSchemaDefaults(t *testing.T) {
+	schemaPath, valuesPath := writeSchemaFiles(t, "db:\n  host: localhost\nservices:\n  - name: web\n  - port: 8080\n")
+
+	schema, err := loadDataSchema(schemaPath)
+	require.NoError(t, err)
+	a := &App{dataSchema: schema}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{valuesPath})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, []string{"name=app"}, false)
+	require.NoError(t, err)
+
+	require.NoError(t, a.validateSchema(schema, data))
+	require.Equal(t, map[string]any{
+		"name":     "app",
+		"replicas": 1,
+		"db":       map[string]any{"host": "localhost", "port": 5432},
+		"services": []any{
+			map[string]any{"name": "web", "port": 80},
+			map[string]any{"port": 8080},
+		},
+	}, data)
+	require.Equal(t, "schema default", a.dataSource("replicas"))
+}
+
+func TestValidateSchemaViolations(t *testing.T) {
+	schemaPath, valuesPath := writeSchemaFiles(t, "db:\n  port: 0\nreplicas: two\n")
+
+	schema, err := loadDataSchema(schemaPath)
+	require.NoError(t, err)
+	a := &App{dataSchema: schema}
+	datasourceUrls, err := a.parseDatasourceUrls([]string{valuesPath})
+	require.NoError(t, err)
+	data, err := a.loadDatasources(datasourceUrls, nil, false)
+	require.NoError(t, err)
+
+	err = a.validateSchema(schema, data)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "(root): missing property 'name'")
+	require.Contains(t, err.Error(), "/db: missing property 'host' (from "+valuesPath+")")
+	require.Contains(t, err.Error(), "/db/port: minimum: got 0, want 1 (from "+valuesPath+")")
+	require.Contains(t, err.Error(), "/replicas: got string, want integer (from "+valuesPath+")")
+}
+
+func TestValidateSchemaJson(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`{"type": "object", "properties": {"port": {"type": "integer"}}}`), os.ModePerm))
+
+	schema, err := loadDataSchema(schemaPath)
+	require.NoError(t, err)
+	a := &App{}
+	require.NoError(t, a.validateSchema(schema, map[string]any{"port": 8080}))
+	require.ErrorContains(t, a.validateSchema(schema, map[string]any{"port": "8080"}), "/port: got string, want integer")
+	_, err = loadDataSchema(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestSchemaDefaultsAreInterpolatedAndChecked(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.yaml")
+	require.NoError(t, os.WriteFile(schemaPath, []byte("properties:\n  url: {type: string, default: \"https://${domain}/api\"}\n"), os.ModePerm))
+	schema, err := loadDataSchema(schemaPath)
+	require.NoError(t, err)
+	manifest, err := loadValuesManifest(writeValuesManifest(t, "values:\n  url:\n    type: string\n    required: true\n"))
+	require.NoError(t, err)
+
+	// Schema defaults are applied before references are resolved and the values manifest is checked
+	a := &App{dataSchema: schema, valuesManifest: manifest, interpolate: true}
+	data, err := a.loadDatasources(nil, []string{"domain=example.com"}, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"domain": "example.com", "url": "https://example.com/api"}, data)
+	require.Equal(t, "schema default", a.dataSource("url"))
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// selectorSegment is a step of a selector: a map key, a list index, or an iteration over all elements
+type selectorSegment struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+// datasourceSelector returns the selector of a datasource, set with the select option
+// or as a URL fragment starting with "." (jq-style) or "$" (JSONPath)
+func datasourceSelector(url *url.URL) string {
+	if selector := url.Query().Get("select"); selector != "" {
+		return selector
+	}
+	if isSelector(url.Fragment) {
+		return url.Fragment
+	}
+	return ""
+}
+
+func isSelector(s string) bool {
+	return strings.HasPrefix(s, ".") || strings.HasPrefix(s, "$")
+}
+
+// parseSelector parses a jq-style (.services[0].env) or JSONPath ($.services[0].env) selector.
+// Keys can be quoted in brackets (["a.b"] or ['a.b']), negative indexes count from the end of a list,
+// and [] or [*] selects from every element of a list or map, producing a list.
+func parseSelector(selector string) ([]selectorSegment, error) {
+	if !isSelector(selector) {
+		return nil, fmt.Errorf("invalid selector %q: must start with . or $", selector)
+	}
+
+	var segments []selectorSegment
+	s := strings.TrimPrefix(selector, "$")
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			return nil, fmt.Errorf("invalid selector %q: recursive descent is not supported", selector)
+		case s[0] == '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			if end > 0 {
+				segments = append(segments, selectorSegment{key: s[:end]})
+			}
+			s = s[end:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid selector %q: missing ]", selector)
+			}
+			segment, err := parseBracketSegment(s[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector %q: %s", selector, err)
+			}
+			segments = append(segments, segment)
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid selector %q: unexpected %q", selector, s)
+		}
+	}
+
+	return segments, nil
+}
+
+func parseBracketSegment(s string) (selectorSegment, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "*" {
+		return selectorSegment{iterate: true}, nil
+	}
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return selectorSegment{key: s[1 : len(s)-1]}, nil
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return selectorSegment{}, fmt.Errorf("invalid index %q", s)
+	}
+	return selectorSegment{index: index, isIndex: true}, nil
+}
+
+// applySelector returns the value of data selected by the segments
+func applySelector(data any, segments []selectorSegment) (any, error) {
+	current := data
+	for i, segment := range segments {
+		switch {
+		case segment.iterate:
+			var elements []any
+			switch v := current.(type) {
+			case []any:
+				elements = v
+			case map[string]any:
+				keys := make([]string, 0, len(v))
+				for k := range v {
+					keys = append(keys, k)
+				}
+				slices.Sort(keys)
+				for _, k := range keys {
+					elements = append(elements, v[k])
+				}
+			default:
+				return nil, fmt.Errorf("cannot iterate over %s", describeValue(current))
+			}
+
+			results := make([]any, 0, len(elements))
+			for _, element := range elements {
+				result, err := applySelector(element, segments[i+1:])
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, result)
+			}
+			return results, nil
+		case segment.isIndex:
+			list, ok := current.([]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s with [%d]", describeValue(current), segment.index)
+			}
+			index := segment.index
+			if index < 0 {
+				index += len(list)
+			}
+			if index < 0 || index >= len(list) {
+				return nil, fmt.Errorf("index %d out of range for a list of %d elements", segment.index, len(list))
+			}
+			current = list[index]
+		default:
+			m, ok := current.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot select key %q from %s", segment.key, describeValue(current))
+			}
+			value, ok := m[segment.key]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", segment.key)
+			}
+			current = value
+		}
+	}
+
+	return current, nil
+}
+
+// selectData applies the selector to the data of a datasource. The result is returned as is when it is mounted
+// under a key, and must otherwise be a map to be merged at the top level.
+func selectData(data any, selector string, key string) (map[string]any, error) {
+	segments, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := applySelector(data, segments)
+	if err != nil {
+		return nil, fmt.Errorf("select %s: %s", selector, err)
+	}
+
+	if key != "" {
+		return map[string]any{key: selected}, nil
+	}
+	m, ok := selected.(map[string]any)
+	if !ok {
+		return nil, errors.New("selector result is not a map, so it must be mounted under a key")
+	}
+	return m, nil
+}
+
+func describeValue(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "a map"
+	case []any:
+		return "a list"
+	default:
+		return fmt.Sprintf("a %T value", v)
+	}
+}
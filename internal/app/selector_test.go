@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var selectorData = map[string]any{
+	"services": []any{
+		map[string]any{"name": "web", "env": map[string]any{"PORT": "8080"}},
+		map[string]any{"name": "worker", "env": map[string]any{"QUEUE": "jobs"}},
+	},
+	"dotted.key": "value",
+}
+
+func TestApplySelector(t *testing.T) {
+	for selector, expected := range map[string]any{
+		".":                       selectorData,
+		".services[0].env":        map[string]any{"PORT": "8080"},
+		"$.services[0].env":       map[string]any{"PORT": "8080"},
+		".services[-1].name":      "worker",
+		".services[].name":        []any{"web", "worker"},
+		"$.services[*].name":      []any{"web", "worker"},
+		`.["dotted.key"]`:         "value",
+		"$['services'][1]['env']": map[string]any{"QUEUE": "jobs"},
+	} {
+		segments, err := parseSelector(selector)
+		require.NoError(t, err, selector)
+		value, err := applySelector(selectorData, segments)
+		require.NoError(t, err, selector)
+		require.Equal(t, expected, value, selector)
+	}
+}
+
+func TestApplySelectorErrors(t *testing.T) {
+	for _, selector := range []string{"services", "..name", ".services[0", ".services[x]"} {
+		_, err := parseSelector(selector)
+		require.Error(t, err, selector)
+	}
+
+	for _, selector := range []string{".missing", ".services[2]", ".services.name", ".services[0].name[0]", `.["dotted.key"][]`} {
+		segments, err := parseSelector(selector)
+		require.NoError(t, err, selector)
+		_, err = applySelector(selectorData, segments)
+		require.Error(t, err, selector)
+	}
+}
+
+func TestSelectData(t *testing.T) {
+	data, err := selectData(selectorData, ".services[0]", "")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"name": "web", "env": map[string]any{"PORT": "8080"}}, data)
+
+	data, err = selectData(selectorData, ".services[].name", "names")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"names": []any{"web", "worker"}}, data)
+
+	_, err = selectData(selectorData, ".services[].name", "")
+	require.ErrorContains(t, err, "must be mounted")
+}
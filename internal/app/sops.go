@@ -0,0 +1,500 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// sopsFormats are the datasource formats that can be encrypted with SOPS
+var sopsFormats = map[string]bool{
+	"yaml": true,
+	"json": true,
+	"env":  true,
+}
+
+// sopsMacOnlyEncryptedInitialization is written to the MAC before any value when only encrypted values are authenticated,
+// as done by SOPS so that the two kinds of MACs never match
+var sopsMacOnlyEncryptedInitialization = []byte{
+	0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0x0b,
+	0x0b, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69,
+}
+
+var sopsValueRegexp = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]`)
+
+type sopsMetadata struct {
+	Age              []sopsAgeRecipient `yaml:"age"`
+	LastModified     string             `yaml:"lastmodified"`
+	Mac              string             `yaml:"mac"`
+	MacOnlyEncrypted bool               `yaml:"mac_only_encrypted"`
+}
+
+// sopsAgeRecipient holds the data key encrypted for an age recipient
+type sopsAgeRecipient struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+// sopsDecrypter decrypts the values of a SOPS document with its data key while computing the document's MAC
+type sopsDecrypter struct {
+	key              []byte
+	mac              hash.Hash
+	macOnlyEncrypted bool
+}
+
+// isSopsEncrypted reports whether the data of the given format is a SOPS encrypted document
+func isSopsEncrypted(format string, data []byte) bool {
+	switch format {
+	case "yaml", "json":
+		var document struct {
+			Sops *sopsMetadata `yaml:"sops"`
+		}
+		return yaml.Unmarshal(data, &document) == nil && document.Sops != nil && document.Sops.Mac != ""
+	case "env":
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if bytes.HasPrefix(line, []byte("sops_mac=")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decryptSopsIfEncrypted returns the data decrypted when it is a SOPS encrypted document of the given format, and as is otherwise
+func decryptSopsIfEncrypted(format string, data []byte) ([]byte, error) {
+	if !sopsFormats[format] || !isSopsEncrypted(format, data) {
+		return data, nil
+	}
+	plaintext, err := decryptSops(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt sops file: %s", err)
+	}
+	return plaintext, nil
+}
+
+// decryptSops decrypts a SOPS encrypted YAML, JSON or dotenv document with the available age identities,
+// verifies its MAC and returns the plaintext document without the SOPS metadata, in the same format.
+// The plaintext is only kept in memory, and errors never include decrypted values.
+func decryptSops(format string, data []byte) ([]byte, error) {
+	switch format {
+	case "yaml", "json":
+		return decryptSopsYaml(format, data)
+	case "env":
+		return decryptSopsEnv(data)
+	default:
+		return nil, fmt.Errorf("sops decryption is not supported for %s", format)
+	}
+}
+
+func decryptSopsYaml(format string, data []byte) ([]byte, error) {
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		documents = append(documents, &document)
+	}
+	if len(documents) == 0 || len(documents[0].Content) == 0 || documents[0].Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("sops metadata not found")
+	}
+
+	// The metadata is read from the first document, and removed from all of them
+	var metadata *sopsMetadata
+	for _, document := range documents {
+		root := document.Content[0]
+		if root.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i < len(root.Content); i += 2 {
+			if root.Content[i].Value != "sops" {
+				continue
+			}
+			if metadata == nil {
+				metadata = &sopsMetadata{}
+				if err := root.Content[i+1].Decode(metadata); err != nil {
+					return nil, fmt.Errorf("invalid sops metadata: %s", err)
+				}
+			}
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			break
+		}
+	}
+	if metadata == nil {
+		return nil, errors.New("sops metadata not found")
+	}
+
+	d, err := newSopsDecrypter(metadata)
+	if err != nil {
+		return nil, err
+	}
+	for _, document := range documents {
+		if err := d.decryptNode(document, nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.verifyMac(metadata); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if format == "json" {
+		var v any
+		if err := documents[0].Decode(&v); err != nil {
+			return nil, err
+		}
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	encoder := yaml.NewEncoder(&buf)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decryptSopsEnv(data []byte) ([]byte, error) {
+	type envLine struct {
+		key   string
+		value string
+	}
+
+	// Lines are read the same way SOPS writes them: comments are dropped, newlines in values are escaped
+	// and the metadata is flattened into keys prefixed with "sops_"
+	var lines []envLine
+	flatMetadata := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid dotenv line %d", i+1)
+		}
+		value = strings.ReplaceAll(value, `\n`, "\n")
+		if name, ok := strings.CutPrefix(key, "sops_"); ok {
+			flatMetadata[name] = value
+			continue
+		}
+		lines = append(lines, envLine{key, value})
+	}
+
+	metadata := &sopsMetadata{
+		LastModified:     flatMetadata["lastmodified"],
+		Mac:              flatMetadata["mac"],
+		MacOnlyEncrypted: flatMetadata["mac_only_encrypted"] == "true",
+	}
+	for i := 0; ; i++ {
+		enc, ok := flatMetadata[fmt.Sprintf("age__list_%d__map_enc", i)]
+		if !ok {
+			break
+		}
+		metadata.Age = append(metadata.Age, sopsAgeRecipient{flatMetadata[fmt.Sprintf("age__list_%d__map_recipient", i)], enc})
+	}
+
+	d, err := newSopsDecrypter(metadata)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		value, err := d.decryptString(line.value, []string{line.key})
+		if err != nil {
+			return nil, err
+		}
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(line.key + "=" + string(quoted) + "\n")
+	}
+	if err := d.verifyMac(metadata); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newSopsDecrypter(metadata *sopsMetadata) (*sopsDecrypter, error) {
+	key, err := sopsDataKey(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &sopsDecrypter{key: key, mac: sha512.New(), macOnlyEncrypted: metadata.MacOnlyEncrypted}
+	if d.macOnlyEncrypted {
+		d.mac.Write(sopsMacOnlyEncryptedInitialization)
+	}
+	return d, nil
+}
+
+// sopsDataKey decrypts the document's data key with the first age identity that matches one of its recipients
+func sopsDataKey(metadata *sopsMetadata) ([]byte, error) {
+	if len(metadata.Age) == 0 {
+		return nil, errors.New("no age recipients found in sops metadata, only age keys are supported")
+	}
+
+	identities, err := sopsAgeIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, recipient := range metadata.Age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(recipient.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+		return io.ReadAll(r)
+	}
+	return nil, errors.New("no age identity matches the recipients of the sops file")
+}
+
+// sopsAgeIdentities reads the age identities from the SOPS_AGE_KEY environment variable, or from the key file
+// in SOPS_AGE_KEY_FILE, defaulting to sops/age/keys.txt in the user's configuration directory
+func sopsAgeIdentities() ([]age.Identity, error) {
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		identities, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("parse SOPS_AGE_KEY: %s", err)
+		}
+		return identities, nil
+	}
+
+	path := os.Getenv("SOPS_AGE_KEY_FILE")
+	if path == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("no age key found, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE: %s", err)
+		}
+		path = filepath.Join(configDir, "sops", "age", "keys.txt")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no age key found, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE: %s", err)
+	}
+	defer f.Close()
+	identities, err := age.ParseIdentities(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("parse age key file %s: %s", path, err)
+	}
+	return identities, nil
+}
+
+// decryptNode decrypts the scalar values in the node in place, in document order.
+// Like in SOPS, items of sequences share the path of the sequence.
+func (d *sopsDecrypter) decryptNode(node *yaml.Node, path []string) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := d.decryptNode(child, path); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			if err := d.decryptNode(node.Content[i+1], append(path, node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return d.decryptScalar(node, path)
+	}
+	return nil
+}
+
+func (d *sopsDecrypter) decryptScalar(node *yaml.Node, path []string) error {
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	if node.Tag != "!!str" || !sopsValueRegexp.MatchString(node.Value) {
+		if !d.macOnlyEncrypted {
+			var value any
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+			d.mac.Write(sopsValueBytes(value))
+		}
+		return nil
+	}
+
+	value, datatype, err := d.decryptValue(node.Value, path)
+	if err != nil {
+		return err
+	}
+	node.Style = 0
+	switch datatype {
+	case "int":
+		node.Tag, node.Value = "!!int", strconv.Itoa(value.(int))
+	case "float":
+		node.Tag, node.Value = "!!float", strconv.FormatFloat(value.(float64), 'f', -1, 64)
+	case "bool":
+		node.Tag, node.Value = "!!bool", strconv.FormatBool(value.(bool))
+	case "time":
+		node.Tag, node.Value = "!!timestamp", string(sopsValueBytes(value))
+	default:
+		node.Tag, node.Value = "!!str", value.(string)
+	}
+	return nil
+}
+
+// decryptString decrypts a value that is either encrypted or kept in plaintext
+func (d *sopsDecrypter) decryptString(value string, path []string) (string, error) {
+	if !sopsValueRegexp.MatchString(value) {
+		if !d.macOnlyEncrypted {
+			d.mac.Write([]byte(value))
+		}
+		return value, nil
+	}
+
+	typed, _, err := d.decryptValue(value, path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(typed), nil
+}
+
+// decrypt decrypts an ENC[AES256_GCM,...] value authenticated with the additional data, returning the plaintext and its type
+func (d *sopsDecrypter) decrypt(value string, additionalData string) ([]byte, string, error) {
+	matches := sopsValueRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, "", errors.New("invalid encrypted value")
+	}
+
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		if parts[i], err = base64.StdEncoding.DecodeString(matches[i+1]); err != nil {
+			return nil, "", fmt.Errorf("invalid encrypted value: %s", err)
+		}
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(d.key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, "", err
+	}
+	return plaintext, matches[4], nil
+}
+
+// decryptValue decrypts a value of the document, which is authenticated with its path
+func (d *sopsDecrypter) decryptValue(value string, path []string) (any, string, error) {
+	plaintext, datatype, err := d.decrypt(value, strings.Join(path, ":")+":")
+	if err != nil {
+		return nil, "", fmt.Errorf("decrypt value at %s: %s", strings.Join(path, "."), err)
+	}
+	typed, ok := sopsTypedValue(plaintext, datatype)
+	if !ok {
+		// The plaintext is a secret, so unlike conversion errors this does not include it
+		return nil, "", fmt.Errorf("value at %s is not a valid %s", strings.Join(path, "."), datatype)
+	}
+	if datatype != "comment" {
+		d.mac.Write(sopsValueBytes(typed))
+	}
+	return typed, datatype, nil
+}
+
+// verifyMac compares the MAC of the decrypted values with the document's MAC,
+// which is encrypted with the data key and authenticated with the last modification time
+func (d *sopsDecrypter) verifyMac(metadata *sopsMetadata) error {
+	lastModified, err := time.Parse(time.RFC3339, metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("invalid sops lastmodified: %s", err)
+	}
+	expected, _, err := d.decrypt(metadata.Mac, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("decrypt sops mac: %s", err)
+	}
+	if actual := fmt.Sprintf("%X", d.mac.Sum(nil)); actual != string(expected) {
+		return errors.New("sops mac mismatch, the file may have been tampered with")
+	}
+	return nil
+}
+
+// sopsTypedValue converts a decrypted plaintext to a value of the type it was encrypted from,
+// reporting whether the plaintext is a valid value of the type
+func sopsTypedValue(plaintext []byte, datatype string) (any, bool) {
+	var value any
+	var err error
+	switch datatype {
+	case "str", "bytes", "comment":
+		return string(plaintext), true
+	case "int":
+		value, err = strconv.Atoi(string(plaintext))
+	case "float":
+		value, err = strconv.ParseFloat(string(plaintext), 64)
+	case "bool":
+		value, err = strconv.ParseBool(string(plaintext))
+	case "time":
+		var t time.Time
+		err = t.UnmarshalText(plaintext)
+		value = t
+	default:
+		return nil, false
+	}
+	return value, err == nil
+}
+
+// sopsValueBytes returns the bytes of a value as they are written to the MAC by SOPS
+func sopsValueBytes(value any) []byte {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case int:
+		return []byte(strconv.Itoa(v))
+	case int64:
+		return []byte(strconv.FormatInt(v, 10))
+	case uint64:
+		return []byte(strconv.FormatUint(v, 10))
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		if v {
+			return []byte("True")
+		}
+		return []byte("False")
+	case time.Time:
+		b, _ := v.MarshalText()
+		return b
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
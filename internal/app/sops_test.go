@@ -0,0 +1,342 @@
+package app
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The test files were encrypted by SOPS for the age identity below
+const sopsTestAgeKey = "AGE-SECRET-KEY-1RSDYTU5JA7M3VMPW0KKK3HMUCUTGGZDSULQCVDUQ2VUA6QLA57RQDX92QJ"
+
+const sopsTestYaml = `#ENC[AES256_GCM,data:s4zF8eKAkNMPYgoJAPiV20y1,iv:ZQpcYuBOHOn/6M/fCocpRl5HA3ewI8c6vytOBfKcljE=,tag:8zneo84Q8tq2LUCVWAeHVQ==,type:comment]
+db:
+    host: ENC[AES256_GCM,data:zhpPl1V0osbQ,iv:cDqXAX5AO8POI9oJFk+Z67XvJ5IqeEPLAMsFN+iol/I=,tag:S1VMh79zgTNS8tzA0nAmYQ==,type:str]
+    port: ENC[AES256_GCM,data:jewbxQ==,iv:Tkms1AIUgF63j++cmQiG6NZ5HPDYtq1tsueIYhtjfFI=,tag:qBDWa60jGkUQPlQTlp7ghw==,type:int]
+    password: ENC[AES256_GCM,data:awFDxu9tig==,iv:EEx/sLWLm4U0+LWIMZmmAmH7/y2RzqsoZQEXaAA6l/Y=,tag:KLHYH50yE1UXIAqwyzM2zA==,type:str]
+    ratio: ENC[AES256_GCM,data:WLY2,iv:BHdruS0sHTXQXQkwa6HLA6dG9+en2c8ktDAdCwl9TvQ=,tag:zoiA96brchQQxWW4Z/3Jbw==,type:float]
+    enabled: ENC[AES256_GCM,data:Tjitsg==,iv:gLtQhPzA3ms2qcdMMQAnyOUh9ZuNmFDX1wVvHrtFUaM=,tag:9nqBzxKDEoRbT5/H/glO7g==,type:bool]
+    version: ENC[AES256_GCM,data:+0yi,iv:MN40fHyHen0nZtjC89sf82guWfRd8aeK5QeMrWPjlwI=,tag:ytDLl6oL22zw10cOWdSDog==,type:str]
+tags:
+    - ENC[AES256_GCM,data:B0hx,iv:t2qag7AREqzItQUMd9Q813S2pGaGxodVYF2uUB9gnbI=,tag:vNPd8v5+qZqQtNt0sHpk/g==,type:str]
+    - ENC[AES256_GCM,data:ltg=,iv:40AjOgLDx6yn3qya+FZjsb2GxYqV3v6vsDktv5IjqRA=,tag:J4G1ueKxmfzngBxlmDByKA==,type:int]
+empty: ""
+nothing: null
+public_unencrypted: visible
+sops:
+    age:
+        - enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBHMVNqcy9uSDJSR3VWMi9V
+            NDQ1RUN1WkhUT1R1WlViLzh2Q0RLSzZQckRNCmh5V3V0SERZT3EyVkdDaDJHcjhp
+            UVJ0cGFNMG9vVHhaZ2pFYURhS3QveEkKLS0tIDFncjBSaDBxVlpEYUlZOFloUUFu
+            aUJRbkFDS3c5STVJdkMxUEdxZzkrcncKGM3wNo6Uvnv0PRFeFFPunZNEn63PViJy
+            58HdfkERA+DytAQeBb0iLO77Zm4b7It7sSpnR1FRD1B0qX519A5zAQ==
+            -----END AGE ENCRYPTED FILE-----
+          recipient: age1vn79y5jc4ms352e5mfa8j5jp7u5cy6es3vkmd7mmr2lheaa4t5sqpdswsz
+    lastmodified: "2026-10-19T16:36:55Z"
+    mac: ENC[AES256_GCM,data:ZBubXnrxIzZF+aBVhmOVitZrFnSeAQRkLEdd4ekAWVzU+86eSI6ixHi/XbUD7tlRjB/b9sdDUIHPp8yhg7Z9M7oyfMXRqwhmUKFJhuTzSZp2hRyRHCiBNPF88rEAyiTFPCxO94bOUeF/IOAppM2y+hGDsD3zDg6l1geJ+Eq6ShA=,iv:+hleV/5Dar8smTq0kadmSael1cqA7ZQVbUbMZAtxEJs=,tag:24+cls7uBJvnciOPTFr7iA==,type:str]
+    unencrypted_suffix: _unencrypted
+    version: 3.13.3
+`
+
+const sopsTestJson = `{
+	"db": {
+		"host": "ENC[AES256_GCM,data:bTYX4A9c3Hg+,iv:jSbIkGHI4a0agt0r3MFKhPkdtcWJ5ZNm9OF9IRPCDvw=,tag:feHzKkhH5wmraMVihtl+Qg==,type:str]",
+		"port": "ENC[AES256_GCM,data:726FfQ==,iv:YeLvKKJOEPk3xken7GAjdX8cfWm8Tqk0XL/3heWp+aY=,tag:V4sLraIeNYUq63NWSVR/NQ==,type:int]",
+		"password": "ENC[AES256_GCM,data:o9auDMrpDQ==,iv:83B9/6JX6Qux3xSQKYzlcKECrr0JpbzvPmRkcK0Z+LE=,tag:FStz+BxAkWzxivghN0GV9Q==,type:str]",
+		"ssl": "ENC[AES256_GCM,data:cjhyMPE=,iv:IhecMv6DMubBfk9NxsdNB41HPLer6N6IrHhxwgSClVE=,tag:5/fmbtyp+5ZDWTlsTKsdsg==,type:bool]"
+	},
+	"tags": [
+		"ENC[AES256_GCM,data:nA==,iv:7bJwo3x0mflrDM0/uTX2ANq4NUYzes7RmAWOsy6g0jc=,tag:LD7lbIxpgEUMZEUPfaqiFg==,type:str]",
+		"ENC[AES256_GCM,data:mQ==,iv:gLXIslri2/3nkKr8ijcZ3d/qt+4WTXySpwtzejTUrn4=,tag:Z79m+6d24lDVIy0n9NMATA==,type:str]"
+	],
+	"ratio": "ENC[AES256_GCM,data:MaoG,iv:X5hok2WpTGUFtdZl1nBPWYGg3pX4mEeuoTHUw99LtyE=,tag:6XNIh70HtaYMwCrrlbBnmg==,type:float]",
+	"sops": {
+		"age": [
+			{
+				"enc": "-----BEGIN AGE ENCRYPTED FILE-----\nYWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBBU2pLM2ljTmNJdXkxVWpW\nU2htYktUaWdpY1hNSkVnYmljZktjR0ozd2tvCmVEWi8vZW1USytrdVRTWnFNS2JZ\nSnJHQm9FSzhpOE0xSzFKMHErNWNGZmsKLS0tIGZ1QjM3aXBTZWcrY0hsQUF5Q2dY\nMDRJeFdjMW1RSTBPNkFYZkQvdmtqc2cKzIaEVHaO0tKg7RRS5EXBmKHW+rcv53kO\n3qj5N+rXyfMkiDWlpYvuAw+DXZk+QOQJTIGOIQwK90Xw/61LRtSuoQ==\n-----END AGE ENCRYPTED FILE-----\n",
+				"recipient": "age1vn79y5jc4ms352e5mfa8j5jp7u5cy6es3vkmd7mmr2lheaa4t5sqpdswsz"
+			}
+		],
+		"lastmodified": "2026-10-19T16:36:55Z",
+		"mac": "ENC[AES256_GCM,data:HZ4FkU1/EPaas+6LMmYRhRkDnQXplinrIn0QLvYlsDkK9nAjbya3XDJ1K+ad9GfoD3ju1yn4S44J03DWeVcdYgO5tCP9wDtpHPzr2wo5ph0iGGjNBDJrLNPZAuFROWkMk4EksqcBsyvhmGRRKD3W/HnXutWn5lMQPLEfE7z8Kf8=,iv:6J6jrf21fkWHmrBvfMpuw30CKVV6j+pXYLKmTpkStZk=,tag:Wf5TkdV0g5muUAg6vITybA==,type:str]",
+		"unencrypted_suffix": "_unencrypted",
+		"version": "3.13.3"
+	}
+}
+`
+
+const sopsTestEnv = `DB_HOST=ENC[AES256_GCM,data:pc8lLvyYJRfn,iv:M7uO2Oce5yUS86XL3Ozt+zE1kfhX0PE0WXkkFy4xF38=,tag:rgmVF/K+IZw8jVngIKpTnA==,type:str]
+DB_PASSWORD=ENC[AES256_GCM,data:oCCPo22cTQ==,iv:MRs1O/rmjZ9hnJ/s2rXM4CcityK1Mbw+M8gSE6yyp9U=,tag:CeqnJQnqAtvLIiehlJ2+WA==,type:str]
+#ENC[AES256_GCM,data:mPduo46EdOc=,iv:NcYHAMGqyJR3oLDQyZ5MxPrTTioWjEK6X+u6qFgsWyw=,tag:4m8BZPmpDbh4q0QVJMQckw==,type:comment]
+MULTI=ENC[AES256_GCM,data:S0IRsEszK2PBJFxEYqjglGI=,iv:9QIg4zTZUmii0pMZkz6VOFDOqV7OpHqShARVfVuAwjs=,tag:miTguIS7bR8fOqa91gEdSg==,type:str]
+sops_age__list_0__map_enc=-----BEGIN AGE ENCRYPTED FILE-----\nYWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBIdjh4TEkwU3Rzd2h0OXlx\nUFRqNmNVZTZtZnBzeG9XVnc5RTVmZGJqV2hFCmlYaDE0ck00QXRycE1CSGpPRmxD\nL05EQlZNb3hGUkhKM2NyU1krOVd0NzQKLS0tIGl2a05aOE9ZLzVIOHFGcEV5bkNP\nMjEySzBDZTVwVEVBR1NnbkdEbEJsNmcK6Pt2S6H+vKN8T5ycDl+1kpQ8NhrI+cXl\n+aMOl+uwVS9wbYGhXpdHyKdcPuJP4xBm82DMIAaDT5Xa7+wEUfR4og==\n-----END AGE ENCRYPTED FILE-----\n
+sops_age__list_0__map_recipient=age1vn79y5jc4ms352e5mfa8j5jp7u5cy6es3vkmd7mmr2lheaa4t5sqpdswsz
+sops_lastmodified=2026-10-19T16:36:55Z
+sops_mac=ENC[AES256_GCM,data:rHW3j7UTUakRLSL7Ls02KgNFO4fDtY3C2Ki5geiruMH11pTErCootvTzu9k+PanauQ563tZ1m4NxkSr6/PWDMpzt46VIdJRy+OM8AJBxmf49fqbKnbHuwnDa36+YPcCStwYJ6tu/oaZVoT8L1bmrBtIIWd+K+1tP56xQqQaoR5Y=,iv:XBhyaCr7bjdHIcUEBPMwoYQQ2mEojt8f7CV45q9biAA=,tag:nTUEWNFf4579I/M2TVOvnQ==,type:str]
+sops_unencrypted_suffix=_unencrypted
+sops_version=3.13.3
+`
+
+// Nested lists, with every value encrypted
+const sopsTestNestedYaml = `services:
+    - name: ENC[AES256_GCM,data:TVWv,iv:/2plaU1hgumu5H19qffGmZz4JWogRskQZqdkX/Jvdlw=,tag:tduvW0wv13kZdgQhzBNvnw==,type:str]
+      password: ENC[AES256_GCM,data:I9Luj7IA,iv:Gtx8+aetI/vWO/ibWpnKLTatOJMTIzr1F/6vCG5iPm0=,tag:syBAcKEeHewC6V3tZ7NkPA==,type:str]
+      ports:
+        - ENC[AES256_GCM,data:kvo=,iv:CNO7h5zr/ex29HaqmlIIHhxMrynHOPhtksK+lYw0g8E=,tag:hNe+TfFzCjZwNgYUa7JJbQ==,type:int]
+        - ENC[AES256_GCM,data:BqXw,iv:TbApzbz3JMu7zsL8ech45sYQmJWYXbYVtm9GH5mauHk=,tag:NGmTECQXAPvIstedWsuPkg==,type:int]
+      tokens:
+        - ENC[AES256_GCM,data:4A==,iv:fZvyP9+VFGlG3zPzBK1W3NgshRpoJVFiMQdreCmkaUU=,tag:MG61iIqaRaTzR7ybZ9AhyA==,type:str]
+        - - ENC[AES256_GCM,data:uw==,iv:sU7EvfyfIBGl4cT/Mlg0g8igRiO76espD/HkDckkYGw=,tag:RKLVUAubQ6RQMte+TsCarA==,type:str]
+          - ENC[AES256_GCM,data:lA==,iv:N10DEASblVOxapo7joa+TwVTQPEIPe71V5PgRXLPNhw=,tag:7nPc5spJZMLpd32Lrwa9QA==,type:str]
+    - name: ENC[AES256_GCM,data:G18=,iv:LFiOLglNAxgbaF9gNIqU+b+lDtpk9x5ephpqGfhIzRA=,tag:gb5saivw3BZ8MQ8zs2qzqA==,type:str]
+      nested:
+        - - password: ENC[AES256_GCM,data:gE/Dhw==,iv:zudV5ZqTfP1P8uAuBgBQ8p/uFemqLZ7BEY1qwoaGk9I=,tag:UdbRNyivNNBOjYe2M5iPaQ==,type:str]
+            enabled: ENC[AES256_GCM,data:RoXcdw==,iv:cLnL5W/fdD4ToOnubApMD1Ne5CrqnZFlGcxOotko0So=,tag:s3LvqJ7w1Gj1e/19nxtUaA==,type:bool]
+matrix:
+    - - ENC[AES256_GCM,data:kg==,iv:zFlg22ZXxTrpyqel2653+K85peDUsCgtm3aacllB91w=,tag:bAlUo4guauOshELNqR3V8Q==,type:int]
+      - ENC[AES256_GCM,data:3Q==,iv:uqMA44ZQcU5iXRhxNXlbT0NncHjqREgitj1CRooxmeM=,tag:GFnCEJez8AJkO+sS4C4dTA==,type:int]
+    - - ENC[AES256_GCM,data:IQ==,iv:tlNo6n7Xff64Qp6V3DJn9gvtoL4DVMrSlaXQABzfH8g=,tag:/Q91r9vmkilVlPV+cV6hCQ==,type:str]
+      - password: ENC[AES256_GCM,data:2Ck64yQ=,iv:AuyJeJrjl69s4qhrc2D3l77+7SHR27Qezue2NPI6glw=,tag:2f3Etdl72zd54cOPACfT6w==,type:str]
+sops:
+    age:
+        - enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBiOTVWQWE3bFN0UlpKaFpC
+            U1FCR0pWUkFseGlxQ282TWNndWMvUEpjSGdzCkFWaTIxWjdIY2lXYTArTWdRZTFW
+            TWo0VzB0YXRmUkE4Q0pDV2lnNVMzaEUKLS0tIElITXBTa1hsQ0Nqdi9yVmttR0Fy
+            Sk12RS9zcTEvVnMvc0gzRU04TDE1R0UKIy/xYFEegk12UHKMdpm8IG0Cg5yEf888
+            K6mPV9gj7grE/jdeFRoxjpsqzauVRCaqfWGOVxvOJU1JCSO1UuqUlQ==
+            -----END AGE ENCRYPTED FILE-----
+          recipient: age1vn79y5jc4ms352e5mfa8j5jp7u5cy6es3vkmd7mmr2lheaa4t5sqpdswsz
+    lastmodified: "2026-10-19T17:22:22Z"
+    mac: ENC[AES256_GCM,data:Zb5QAZyav/9RIj0MdLBl/YD6j3KcjRnCzz8upo/M5ZtRI3ssITZ7vJU445hPLkyhfi1Jq/IkXeg+MpMc+vS7N5ZvlhaeDkwA30AQZQNAVHLwaZvp1yOk+ewUiiGYsytFm8daSm5m+UczSEul7PdA6XSkNUopslnuKUtN9NhVrcs=,iv:hxBKBdA3O/zwh8cLoJXmf+ZaUADsEQCXJvB+D2lGCA4=,tag:/I3UB6T22b3XizPaQWSElg==,type:str]
+    unencrypted_suffix: _unencrypted
+    version: 3.13.3
+`
+
+// Only the values under keys matching encrypted_regex are encrypted, while the MAC covers every value
+const sopsTestEncryptedRegexYaml = `services:
+    - name: web
+      password: ENC[AES256_GCM,data:1wbOMYCk,iv:lqUOSPrGavxjtfLU7neQanfWJ5zODEiRm95DosPTjqU=,tag:HKJEtkzsNk/A/Sty6tvioQ==,type:str]
+      ports:
+        - 80
+        - 443
+      tokens:
+        - ENC[AES256_GCM,data:bQ==,iv:tBO9+xh4KoC6Vfmkvh7ilx2hXppK6/8GYA9BqpIwhYw=,tag:kArZUjxLUHklw1axcj4e6w==,type:str]
+        - - ENC[AES256_GCM,data:2A==,iv:RYuM3sh5UQoPjJAgg/sBYRin7FsDL5FBDKJmX/eMpp8=,tag:o84rs48+t02dkMtVX1meYQ==,type:str]
+          - ENC[AES256_GCM,data:Ng==,iv:4xAruMsQJDpUnmzLqWXBLPQ5fJ+Ht87gqwNy6bFuh1Q=,tag:oSiz548pjEEArFSVIUZFtg==,type:str]
+    - name: db
+      nested:
+        - - password: ENC[AES256_GCM,data:Q4oDxg==,iv:AhHFngoeBOY2lxirrQ/w7WPcgkOZwCWTLiZFPM+q7tM=,tag:9/PbJk9GvK6by3Hqy0p25A==,type:str]
+            enabled: true
+matrix:
+    - - 1
+      - 2
+    - - x
+      - password: ENC[AES256_GCM,data:q8q4T18=,iv:VhiyFpCE8G9pod8zzhxOJU39N6ikiFZpFbsbnutb85E=,tag:Fkw0GWZimoSr5wMjyQKRHw==,type:str]
+sops:
+    age:
+        - enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSAyRHFGOU5lUDZZN0hOZ2k0
+            azdKYWlyOVRSN00xQ2pnTTVGWUwrUlJndlJrCjljR3k5elZ0Ukd6aFMvc3FySkZE
+            QkJmYU83UHlLa1o1Vlk4UHF0LzFJTU0KLS0tIEtIVnRFM2Zxdm5vWXBuT3B0V1Bv
+            M2dJZXR6eUpsVHIvQXhwcENQb3JtT3MK+f6X1LLaNkf8jYvgfbBN8ZgfmSfgm0ld
+            JZp6jpcOq6BAwLdxRzsb/iakEECWxsdICtAKnxQAnEoVBasbSqqwjQ==
+            -----END AGE ENCRYPTED FILE-----
+          recipient: age1vn79y5jc4ms352e5mfa8j5jp7u5cy6es3vkmd7mmr2lheaa4t5sqpdswsz
+    encrypted_regex: ^(password|tokens)$
+    lastmodified: "2026-10-19T17:22:22Z"
+    mac: ENC[AES256_GCM,data:y6XmaV5oj/k4BLgtJpzS7tPq4x87GpLMdj6niGo2hadYw0vKvlTlnXzS7hjSfUUuhafHstXBSuF0mZ59pR3e+OcmEhAcDLZXXECr46oi6d5BkF9hWK5DyRDJmnebji5ekSeohi9k8F2FrC0QiEk+T5RFUmPZLKFbAb56byuH29I=,iv:+QHrAjEmt8iSFcxh7mgCbp9kNtlZL4GN+vC3EzALT1Q=,tag:B2UpvRcOGL5O3BKPPpP2vw==,type:str]
+    version: 3.13.3
+`
+
+// Like sopsTestEncryptedRegexYaml, with a MAC that only covers the encrypted values (mac_only_encrypted)
+const sopsTestMacOnlyEncryptedYaml = `services:
+    - name: web
+      password: ENC[AES256_GCM,data:lPnQVH4m,iv:/ED2y1AFUHqZod7w6QwtMJZHB2/1KGe1WSHvZ3iSaZo=,tag:rbHucyDDdEcCYZYBPFbQcQ==,type:str]
+      ports:
+        - 80
+        - 443
+      tokens:
+        - ENC[AES256_GCM,data:Fw==,iv:nXPeHMawy3u0atySUjBlNVnigZxxQ0xpEFqmyTFVq24=,tag:FgZtJxu0AVVKAo1BwTIyjA==,type:str]
+        - - ENC[AES256_GCM,data:Ow==,iv:sQVpDlxWw+y+g4GYsyWMoc1WV0da+UUT1pDojeZKd3E=,tag:MOcEG2JBCRaiqjHka9WW7w==,type:str]
+          - ENC[AES256_GCM,data:+g==,iv:v/Fk+riSogVQfQoy0Ri0SKxYKOyX6iT6Vj5exF0zdew=,tag:v4tN/EvuwW0Q3vYiIjaOzg==,type:str]
+    - name: db
+      nested:
+        - - password: ENC[AES256_GCM,data:Cp0tEg==,iv:u1PeY8kCiY7ygtcg5gK1hltGuD9yc7ldPb8MTk+qi/Y=,tag:iHJ+ziSzmHc9LHy78BZ2UQ==,type:str]
+            enabled: true
+matrix:
+    - - 1
+      - 2
+    - - x
+      - password: ENC[AES256_GCM,data:yrjfecw=,iv:VakAe4xgdA9o8FBTg1Hi3kxmUM4h873QcFJ+esloZeQ=,tag:EbiKTjIurE1a4mhpt7n0xg==,type:str]
+sops:
+    age:
+        - enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBEc3U0d1VhY0tnVmVVRUpK
+            bUdmVzRXdjhUNHFiaHFYMnk3TTVQZEM4TFVJCjMzQ05sTE01L0IydmdsU0lIQ3dw
+            MENMMmJZSUJWc2grZTQ0U3ZFd2laSlkKLS0tIEthTjVSNllkZndESUYwOFZnKzk3
+            eC9sazFOMHFLSTVtd2lieUxnVU1zNG8KhKYCm8+8AKyo/AywnDmhvuMnVchXQM3I
+            DwZbST+94gx/gRToolBsz0jzktD1iPNkrUy9tkYTGBQc144IS3VMbA==
+            -----END AGE ENCRYPTED FILE-----
+          recipient: age1vn79y5jc4ms352e5mfa8j5jp7u5cy6es3vkmd7mmr2lheaa4t5sqpdswsz
+    encrypted_regex: ^(password|tokens)$
+    lastmodified: "2026-10-19T17:22:22Z"
+    mac: ENC[AES256_GCM,data:IuGOEg+G6jD4Ke/TNXpxdB0Tw84HCIWXZsW6vpjiPJvtl1s42sHsbLLq+lqwljPk+s+j3+W7MN3WkANlzSyeQBoXLlHiBXhQ10lbE5OJHrZNPXUrvvJfW7xZ2s1d8gaFIfTDrV1jSxUw1o+Y0xyd7b67K2ILtFUSSiW5/yyFyag=,iv:0UoMxK46qWve9a8vJ1asp7NdFXCKE+WyaUTNBb7rnEg=,tag:dtMpO9eKRAJ+AGDnP7Q5Sg==,type:str]
+    mac_only_encrypted: true
+    version: 3.13.3
+`
+
+// Only the values under keys with the encrypted_suffix are encrypted
+const sopsTestEncryptedSuffixYaml = `db:
+    host: localhost
+    password_secret: ENC[AES256_GCM,data:1cK8OnShPw==,iv:49ASRgIScLl0cl/pzrYp2zU0ehWVUwpEfD+h/1mMZqQ=,tag:zI+k+XgDCNWlGYjGpjNf3A==,type:str]
+    replicas:
+        - a
+        - b
+tokens_secret:
+    - ENC[AES256_GCM,data:ag==,iv:rK0S+0cLV87ao57og3RdLg6B5gJs+R6XLQfRaTFPKHY=,tag:D4kOClnv+CCvEy0Cx0kS8Q==,type:str]
+    - - ENC[AES256_GCM,data:lQ==,iv:oVL/7Qseg8TaZ1MAXQTegLUFrsb1rjcXCptlRmt01MI=,tag:hakBaiz4370OyG010tMZ4Q==,type:str]
+sops:
+    age:
+        - enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBMTlBqV3JJajBHdXFCTkFo
+            cDF6TUhkcnhaYW1TYlNpR1p0WDhoSnZQVW1ZClBjL25yWXhobnZNTkRMQ3dGUkN4
+            NVZrTkhwY25hT0YrRzNWRHA5Y1VOM0EKLS0tIEpoZ3VleVJ5K1NnZStxN0hMSURq
+            ODAwcXZoai9FYUpvcjdGSXRRa2lsTTQK5Cg4GFCattNZU4kmPfjQVKqz4NeEJn61
+            en7eVMuKMSC8D/gb/4cjyHsF5ROlpyBouMah1bVsP7zY7iaengy9wg==
+            -----END AGE ENCRYPTED FILE-----
+          recipient: age1vn79y5jc4ms352e5mfa8j5jp7u5cy6es3vkmd7mmr2lheaa4t5sqpdswsz
+    encrypted_suffix: _secret
+    lastmodified: "2026-10-19T17:22:27Z"
+    mac: ENC[AES256_GCM,data:K3odMLfxiasBdczX2o3M8tTlTqOBd5fWvpMWSjuRnt0anAS4YE11JJ8Zl6KCNt29OsZeLwGvsyGiRPnHhJCfC82WDU1MiHAnzd2OiJ8ZBzlsvZWYKXPkMYv6lQC9m5a0UXxFN4FudCIQq7uxhzLFluOQjAOp+mc+9kYJjEHIcpg=,iv:Vyr0QY7ghDs2KRuX26t/j1lZAdTmAcoiJu5lVsr14cc=,tag:imD5BCyci+ycvBGjkmQ/GA==,type:str]
+    version: 3.13.3
+`
+
+func loadSopsFile(t *testing.T, name string, content string) (map[string]any, error) {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), os.ModePerm))
+	url, err := url.Parse(path)
+	require.NoError(t, err)
+
+	a := &App{}
+	ds, rc, err := a.createDatasourceFromURL(url)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ds.Load()
+}
+
+func TestSopsFileLoad(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", sopsTestAgeKey)
+
+	data, err := loadSopsFile(t, "secrets.yaml", sopsTestYaml)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"db": map[string]any{
+			"host":     "localhost",
+			"port":     5432,
+			"password": "hunter2",
+			"ratio":    0.5,
+			"enabled":  true,
+			"version":  "1.0",
+		},
+		"tags":               []any{"web", 42},
+		"empty":              "",
+		"nothing":            nil,
+		"public_unencrypted": "visible",
+	}, data)
+
+	data, err = loadSopsFile(t, "secrets.json", sopsTestJson)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"db":    map[string]any{"host": "localhost", "port": float64(5432), "password": "hunter2", "ssl": false},
+		"tags":  []any{"a", "b"},
+		"ratio": 1.5,
+	}, data)
+
+	data, err = loadSopsFile(t, "secrets.env", sopsTestEnv)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"DB_HOST": "localhost", "DB_PASSWORD": "hunter2", "MULTI": "line one\nline two"}, data)
+}
+
+func TestSopsFileLoadNestedAndPartiallyEncrypted(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", sopsTestAgeKey)
+
+	nested := map[string]any{
+		"services": []any{
+			map[string]any{"name": "web", "password": "s3cret", "ports": []any{80, 443}, "tokens": []any{"a", []any{"b", "c"}}},
+			map[string]any{"name": "db", "nested": []any{[]any{map[string]any{"password": "deep", "enabled": true}}}},
+		},
+		"matrix": []any{[]any{1, 2}, []any{"x", map[string]any{"password": "inner"}}},
+	}
+	for _, content := range []string{sopsTestNestedYaml, sopsTestEncryptedRegexYaml, sopsTestMacOnlyEncryptedYaml} {
+		data, err := loadSopsFile(t, "secrets.yaml", content)
+		require.NoError(t, err)
+		require.Equal(t, nested, data)
+	}
+
+	data, err := loadSopsFile(t, "secrets.yaml", sopsTestEncryptedSuffixYaml)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"db":            map[string]any{"host": "localhost", "password_secret": "hunter2", "replicas": []any{"a", "b"}},
+		"tokens_secret": []any{"a", []any{"b"}},
+	}, data)
+
+	// Unencrypted values are only authenticated when the MAC covers every value
+	_, err = loadSopsFile(t, "secrets.yaml", strings.Replace(sopsTestEncryptedRegexYaml, "name: web", "name: api", 1))
+	require.ErrorContains(t, err, "sops mac mismatch")
+	data, err = loadSopsFile(t, "secrets.yaml", strings.Replace(sopsTestMacOnlyEncryptedYaml, "name: web", "name: api", 1))
+	require.NoError(t, err)
+	require.Equal(t, "api", data["services"].([]any)[0].(map[string]any)["name"])
+	_, err = loadSopsFile(t, "secrets.yaml", strings.Replace(sopsTestEncryptedSuffixYaml, "host: localhost", "host: changed", 1))
+	require.ErrorContains(t, err, "sops mac mismatch")
+}
+
+func TestSopsFileLoadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# test key\n"+sopsTestAgeKey+"\n"), os.ModePerm))
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", path)
+
+	data, err := loadSopsFile(t, "secrets.env", sopsTestEnv)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", data["DB_PASSWORD"])
+
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join(t.TempDir(), "missing.txt"))
+	_, err = loadSopsFile(t, "secrets.env", sopsTestEnv)
+	require.ErrorContains(t, err, "no age key found")
+}
+
+func TestSopsFileLoadErrors(t *testing.T) {
+	// An identity that is not a recipient of the file
+	t.Setenv("SOPS_AGE_KEY", "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX")
+	_, err := loadSopsFile(t, "secrets.yaml", sopsTestYaml)
+	require.ErrorContains(t, err, "no age identity matches")
+
+	// A changed plaintext value does not match the MAC
+	t.Setenv("SOPS_AGE_KEY", sopsTestAgeKey)
+	_, err = loadSopsFile(t, "secrets.yaml", strings.Replace(sopsTestYaml, "public_unencrypted: visible", "public_unencrypted: changed", 1))
+	require.ErrorContains(t, err, "sops mac mismatch")
+
+	// An encrypted value moved to another key does not authenticate
+	tampered := strings.Replace(sopsTestEnv, "DB_PASSWORD=", "DB_PASS=", 1)
+	_, err = loadSopsFile(t, "secrets.env", tampered)
+	require.ErrorContains(t, err, "decrypt value at DB_PASS")
+	require.NotContains(t, err.Error(), "hunter2")
+
+	// The type is not authenticated, and a plaintext that is not of the type is not shown
+	tampered = strings.Replace(sopsTestYaml, "tag:KLHYH50yE1UXIAqwyzM2zA==,type:str]", "tag:KLHYH50yE1UXIAqwyzM2zA==,type:int]", 1)
+	_, err = loadSopsFile(t, "secrets.yaml", tampered)
+	require.EqualError(t, err, "decrypt sops file: value at db.password is not a valid int")
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"errors"
+	"net/url"
 )
 
 var (
@@ -13,6 +14,8 @@ var (
 	ErrInputStringAndExcludeConflict = errors.New("exclude cannot be used with input string")
 	ErrNoOutput                      = errors.New("output is required")
 	ErrDataRequired                  = errors.New("data is required through the datasource or data flags")
+	ErrMultipleStdinDatasources      = errors.New("only one datasource can read from stdin")
+	ErrStdinDatasourceWithoutInput   = errors.New("input, input-file or input-dir is required when stdin is used as a datasource")
 )
 
 func (a *App) validateFlags(
@@ -24,6 +27,11 @@ func (a *App) validateFlags(
 	excludePatterns []string,
 	engine string,
 ) error {
+	// Stdin is read as the template only when no datasource reads from it
+	if countStdinDatasources(datasource) > 0 && len(inputString) == 0 && len(inputDir) == 0 && len(inputFile) == 0 {
+		return ErrStdinDatasourceWithoutInput
+	}
+
 	if len(inputString) == 0 && len(inputDir) == 0 && len(inputFile) == 0 {
 		return ErrNoInput
 	}
@@ -52,5 +60,21 @@ func (a *App) validateFlags(
 		return ErrInputStringAndExcludeConflict
 	}
 
+	if countStdinDatasources(datasource) > 1 {
+		return ErrMultipleStdinDatasources
+	}
+
 	return nil
 }
+
+// countStdinDatasources returns the number of datasources that read from stdin
+func countStdinDatasources(datasource []string) int {
+	count := 0
+	for _, ds := range datasource {
+		// Schemes are case-insensitive, so they are compared once parsed, like createDatasourceFromURL does
+		if u, err := url.Parse(ds); err == nil && u.Scheme == "stdin" {
+			count++
+		}
+	}
+	return count
+}
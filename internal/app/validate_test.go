@@ -140,3 +140,63 @@ func TestValidateFlagsInputStringAndExcludeConflict(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, ErrInputStringAndExcludeConflict)
 }
+
+func TestValidateFlagsStdinDatasource(t *testing.T) {
+	app := NewApp("test")
+	err := app.validateFlags(
+		"",
+		"",
+		"input.txt",
+		[]string{"stdin://?format=json"},
+		nil,
+		nil,
+		"",
+	)
+	require.NoError(t, err)
+}
+
+func TestValidateFlagsStdinDatasourceWithoutInput(t *testing.T) {
+	app := NewApp("test")
+	err := app.validateFlags(
+		"",
+		"",
+		"",
+		[]string{"stdin://?format=json"},
+		nil,
+		nil,
+		"",
+	)
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrStdinDatasourceWithoutInput)
+}
+
+func TestValidateFlagsMultipleStdinDatasources(t *testing.T) {
+	app := NewApp("test")
+	err := app.validateFlags(
+		"",
+		"",
+		"input.txt",
+		[]string{"stdin://?format=json", "stdin://?format=yaml"},
+		nil,
+		nil,
+		"",
+	)
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrMultipleStdinDatasources)
+}
+
+func TestValidateFlagsMultipleStdinDatasourcesMixedCase(t *testing.T) {
+	app := NewApp("test")
+	err := app.validateFlags(
+		"",
+		"",
+		"input.txt",
+		[]string{"STDIN://", "stdin://"},
+		nil,
+		nil,
+		"",
+	)
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrMultipleStdinDatasources)
+	require.Equal(t, 1, countStdinDatasources([]string{"Stdin://?format=json", "values.yaml"}))
+}
@@ -0,0 +1,75 @@
+package datasources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type ConsulOptions struct {
+	Address string // Consul HTTP API address, e.g. http://127.0.0.1:8500
+	Token   string
+}
+
+// ConsulDatasource reads a single key, or all keys under a prefix ending with "/", from the Consul KV store.
+// The key hierarchy is turned into nested maps, and values that look like JSON or YAML are parsed.
+type ConsulDatasource struct {
+	key     string
+	options ConsulOptions
+	client  *http.Client
+}
+
+func NewConsulDatasource(key string, options ConsulOptions, client *http.Client) *ConsulDatasource {
+	return &ConsulDatasource{key, options, client}
+}
+
+func (ds *ConsulDatasource) Load() (map[string]any, error) {
+	u, err := url.JoinPath(ds.options.Address, "v1", "kv", ds.key)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(ds.key, "/") {
+		u = strings.TrimSuffix(u, "/") + "/?recurse=true"
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ds.options.Token != "" {
+		req.Header.Set("X-Consul-Token", ds.options.Token)
+	}
+
+	res, err := ds.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("key %q not found", ds.key)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	var entries []struct {
+		Key   string `json:"Key"`
+		Value []byte `json:"Value"` // Base64 encoded in the response
+	}
+	if err := json.NewDecoder(res.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Key, "/") { // Skip folders
+			continue
+		}
+		pairs[entry.Key] = entry.Value
+	}
+
+	return kvToNested(ds.key, pairs)
+}
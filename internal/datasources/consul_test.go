@@ -0,0 +1,54 @@
+package datasources
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulLoad(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "consul-token", r.Header.Get("X-Consul-Token"))
+		switch {
+		case r.URL.Path == "/v1/kv/config/app/" && r.URL.Query().Get("recurse") == "true":
+			_, _ = fmt.Fprintf(w, `[
+				{"Key": "config/app/", "Value": null},
+				{"Key": "config/app/name", "Value": "%s"},
+				{"Key": "config/app/db/host", "Value": "%s"},
+				{"Key": "config/app/features", "Value": "%s"},
+				{"Key": "config/app/limits", "Value": "%s"}
+			]`, b64([]byte("web")), b64([]byte("localhost")), b64([]byte(`["a", "b"]`)), b64([]byte("---\ncpu: 2\nmemory: 1Gi")))
+		case r.URL.Path == "/v1/kv/config/app/name":
+			_, _ = fmt.Fprintf(w, `[{"Key": "config/app/name", "Value": "%s"}]`, b64([]byte("web")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	options := ConsulOptions{Address: ts.URL, Token: "consul-token"}
+
+	ds := NewConsulDatasource("config/app/", options, ts.Client())
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"name":     "web",
+		"db":       map[string]any{"host": "localhost"},
+		"features": []any{"a", "b"},
+		"limits":   map[string]any{"cpu": 2, "memory": "1Gi"},
+	}, data)
+
+	ds = NewConsulDatasource("config/app/name", options, ts.Client())
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"name": "web"}, data)
+
+	ds = NewConsulDatasource("config/missing", options, ts.Client())
+	data, err = ds.Load()
+	require.ErrorContains(t, err, "not found")
+	require.Nil(t, data)
+}
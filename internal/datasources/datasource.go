@@ -1,5 +1,44 @@
 package datasources
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type Datasource interface {
 	Load() (map[string]any, error)
 }
+
+// ValueDatasource is implemented by datasources that can provide data that is not a map, such as a list of records.
+// Such data can only be used when the datasource is mounted under a key.
+type ValueDatasource interface {
+	Datasource
+	LoadValue() (any, error)
+}
+
+var ErrMountRequired = errors.New("datasource provides a list and must be mounted under a key")
+
+// setNestedValue sets value in data at the given key path, creating intermediate maps as needed
+func setNestedValue(data map[string]any, path []string, value any) error {
+	parent := data
+	for i, key := range path[:len(path)-1] {
+		switch child := parent[key].(type) {
+		case map[string]any:
+			parent = child
+		case nil:
+			m := make(map[string]any)
+			parent[key] = m
+			parent = m
+		default:
+			return fmt.Errorf("key %q conflicts with a value already set at %q", strings.Join(path, "."), strings.Join(path[:i+1], "."))
+		}
+	}
+
+	key := path[len(path)-1]
+	if _, ok := parent[key].(map[string]any); ok {
+		return fmt.Errorf("key %q conflicts with nested keys already set under it", strings.Join(path, "."))
+	}
+	parent[key] = value
+	return nil
+}
@@ -1,41 +1,158 @@
 package datasources
 
 import (
-	"bytes"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-
-	"github.com/hashicorp/go-envparse"
 )
 
+const envNestingSeparator = "__"
+
+var envNumberRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+type EnvOptions struct {
+	Prefix      string  // Only load variables starting with this prefix
+	StripPrefix bool    // Remove the prefix from the loaded keys
+	KeyCase     string  // Convert keys to "lower" or "upper" case
+	Nest        bool    // Split keys on double underscores into nested maps (DB__HOST -> db.host)
+	InferTypes  bool    // Convert numeric and boolean values to numbers and booleans
+	Default     *string // Value used when a single variable is not set
+	Optional    bool    // Load nothing instead of failing when a single variable is not set
+}
+
 type EnvDatasource struct {
 	variable string
+	options  EnvOptions
 }
 
 func NewEnvDatasource(variable string) *EnvDatasource {
-	return &EnvDatasource{variable}
+	return &EnvDatasource{variable, EnvOptions{}}
+}
+
+func NewEnvDatasourceWithOptions(variable string, options EnvOptions) *EnvDatasource {
+	return &EnvDatasource{variable, options}
 }
 
 func (ds *EnvDatasource) Load() (map[string]any, error) {
 	data := make(map[string]any)
 
 	if ds.variable == "" { // If no variable is provided, we use all environment variables
-		r := bytes.NewReader([]byte(strings.Join(os.Environ(), "\n")))
-		env, err := envparse.Parse(r)
-		if err != nil {
-			return nil, fmt.Errorf("parse environment variables: %s", err)
-		}
-		for k, v := range env {
-			data[k] = v
+		variables := make(map[string]string) // Variable loaded as each key, to report keys that collide once converted
+		for _, kv := range os.Environ() {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || k == "" || !strings.HasPrefix(k, ds.options.Prefix) { // Skip entries like "=C:=C:\" on Windows
+				continue
+			}
+			key, err := ds.key(k)
+			if err != nil {
+				return nil, err
+			}
+			if other, ok := variables[key]; ok {
+				names := []string{other, k}
+				slices.Sort(names)
+				return nil, fmt.Errorf("environment variables %q and %q are both loaded as key %q", names[0], names[1], key)
+			}
+			variables[key] = k
+			if err := ds.set(data, k, key, v); err != nil {
+				return nil, err
+			}
 		}
 	} else {
-		value := os.Getenv(ds.variable)
-		if len(value) == 0 {
-			return nil, fmt.Errorf("environment variable %q not found", ds.variable)
+		value, ok := os.LookupEnv(ds.variable)
+		if !ok {
+			switch {
+			case ds.options.Default != nil:
+				value = *ds.options.Default
+			case ds.options.Optional:
+				return data, nil
+			default:
+				return nil, fmt.Errorf("environment variable %q not found", ds.variable)
+			}
+		}
+		key, err := ds.key(ds.variable)
+		if err != nil {
+			return nil, err
+		}
+		if err := ds.set(data, ds.variable, key, value); err != nil {
+			return nil, err
 		}
-		data[ds.variable] = value
 	}
 
 	return data, nil
 }
+
+// key returns the key of the variable after the key conversions from the options, or "" if it is not loaded
+func (ds *EnvDatasource) key(variable string) (string, error) {
+	key := variable
+	if ds.options.StripPrefix {
+		key = strings.TrimPrefix(key, ds.options.Prefix)
+	}
+
+	switch ds.options.KeyCase {
+	case "":
+		return key, nil
+	case "lower":
+		return strings.ToLower(key), nil
+	case "upper":
+		return strings.ToUpper(key), nil
+	default:
+		return "", fmt.Errorf("unsupported key case: %s", ds.options.KeyCase)
+	}
+}
+
+// set stores the variable in data under its key, applying the value conversions from the options
+func (ds *EnvDatasource) set(data map[string]any, variable string, key string, value string) error {
+	if key == "" {
+		return nil
+	}
+
+	var v any = value
+	if ds.options.InferTypes {
+		v = inferEnvType(value)
+	}
+
+	if !ds.options.Nest {
+		data[key] = v
+		return nil
+	}
+
+	path := strings.Split(key, envNestingSeparator)
+	for _, p := range path {
+		if p == "" {
+			return fmt.Errorf("environment variable %q: empty key segment", variable)
+		}
+	}
+	if err := setNestedValue(data, path, v); err != nil {
+		return fmt.Errorf("environment variable %q: %s", variable, err)
+	}
+	return nil
+}
+
+func inferEnvType(value string) any {
+	switch strings.ToLower(value) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if !envNumberRegex.MatchString(value) {
+		return value
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if strconv.FormatInt(i, 10) != value { // Keep values with leading zeros, such as "007", as strings
+			return value
+		}
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		if strconv.FormatFloat(f, 'f', -1, 64) != value { // Keep values such as versions ("1.10") as strings
+			return value
+		}
+		return f
+	}
+	return value
+}
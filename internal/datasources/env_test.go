@@ -1,21 +1,21 @@
 package datasources
 
 import (
-	"bytes"
 	"os"
 	"strings"
 	"testing"
 
-	"github.com/hashicorp/go-envparse"
 	"github.com/stretchr/testify/require"
 )
 
 func TestEnvLoadFromEnvironment(t *testing.T) {
+	t.Setenv("RENDERKIT_QUOTED_VAR", `say "hello"`)
+	t.Setenv("RENDERKIT_MULTILINE_VAR", "line1\nline2")
+	t.Setenv("RENDERKIT_EMPTY_VAR", "")
+
 	var expectedData = map[string]any{}
-	r := bytes.NewReader([]byte(strings.Join(os.Environ(), "\n")))
-	env, err := envparse.Parse(r)
-	require.NoError(t, err)
-	for k, v := range env {
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
 		expectedData[k] = v
 	}
 
@@ -23,6 +23,9 @@ func TestEnvLoadFromEnvironment(t *testing.T) {
 	data, err := ds.Load()
 	require.NoError(t, err)
 	require.Equal(t, expectedData, data)
+	require.Equal(t, `say "hello"`, data["RENDERKIT_QUOTED_VAR"])
+	require.Equal(t, "line1\nline2", data["RENDERKIT_MULTILINE_VAR"])
+	require.Equal(t, "", data["RENDERKIT_EMPTY_VAR"])
 }
 
 func TestEnvDatasourceLoadVariable(t *testing.T) {
@@ -45,3 +48,115 @@ func TestEnvDatasourceLoadVariableNotFound(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, data)
 }
+
+func TestEnvDatasourceLoadEmptyVariable(t *testing.T) {
+	t.Setenv("RENDERKIT_EMPTY_VAR", "")
+
+	ds := NewEnvDatasource("RENDERKIT_EMPTY_VAR")
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"RENDERKIT_EMPTY_VAR": ""}, data)
+}
+
+func TestEnvDatasourceLoadVariableDefault(t *testing.T) {
+	defaultValue := "fallback"
+	ds := NewEnvDatasourceWithOptions("RENDERKIT_NON_EXISTING_ENV_VAR", EnvOptions{Default: &defaultValue})
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"RENDERKIT_NON_EXISTING_ENV_VAR": "fallback"}, data)
+
+	// The default is not used when the variable is set, even if it is empty
+	t.Setenv("RENDERKIT_EMPTY_VAR", "")
+	ds = NewEnvDatasourceWithOptions("RENDERKIT_EMPTY_VAR", EnvOptions{Default: &defaultValue})
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"RENDERKIT_EMPTY_VAR": ""}, data)
+}
+
+func TestEnvDatasourceLoadVariableOptional(t *testing.T) {
+	ds := NewEnvDatasourceWithOptions("RENDERKIT_NON_EXISTING_ENV_VAR", EnvOptions{Optional: true})
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Empty(t, data)
+}
+
+func TestEnvDatasourceLoadWithOptions(t *testing.T) {
+	t.Setenv("RENDERKIT_TEST_NAME", "app")
+	t.Setenv("RENDERKIT_TEST_DB__HOST", "localhost")
+	t.Setenv("RENDERKIT_TEST_DB__PORT", "5432")
+	t.Setenv("RENDERKIT_TEST_DB__RATIO", "0.5")
+	t.Setenv("RENDERKIT_TEST_DEBUG", "true")
+	t.Setenv("RENDERKIT_TEST_CODE", "007")
+
+	expectedData := map[string]any{
+		"name": "app",
+		"db": map[string]any{
+			"host":  "localhost",
+			"port":  int64(5432),
+			"ratio": 0.5,
+		},
+		"debug": true,
+		"code":  "007",
+	}
+
+	ds := NewEnvDatasourceWithOptions("", EnvOptions{
+		Prefix:      "RENDERKIT_TEST_",
+		StripPrefix: true,
+		KeyCase:     "lower",
+		Nest:        true,
+		InferTypes:  true,
+	})
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestInferEnvType(t *testing.T) {
+	require.Equal(t, int64(42), inferEnvType("42"))
+	require.Equal(t, int64(-3), inferEnvType("-3"))
+	require.Equal(t, 0.5, inferEnvType("0.5"))
+	require.Equal(t, true, inferEnvType("TRUE"))
+	require.Equal(t, "007", inferEnvType("007"))
+	require.Equal(t, "1.10", inferEnvType("1.10"))
+	require.Equal(t, "1e3", inferEnvType("1e3"))
+	require.Equal(t, "1.0", inferEnvType("1.0"))
+}
+
+func TestEnvDatasourceLoadWithPrefix(t *testing.T) {
+	t.Setenv("RENDERKIT_TEST_NAME", "app")
+	t.Setenv("RENDERKIT_TEST_PORT", "80")
+
+	expectedData := map[string]any{
+		"RENDERKIT_TEST_NAME": "app",
+		"RENDERKIT_TEST_PORT": "80",
+	}
+
+	ds := NewEnvDatasourceWithOptions("", EnvOptions{Prefix: "RENDERKIT_TEST_"})
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestEnvDatasourceLoadNestingConflict(t *testing.T) {
+	t.Setenv("RENDERKIT_TEST_DB", "db")
+	t.Setenv("RENDERKIT_TEST_DB__HOST", "localhost")
+
+	ds := NewEnvDatasourceWithOptions("", EnvOptions{Prefix: "RENDERKIT_TEST_", Nest: true})
+	_, err := ds.Load()
+	require.Error(t, err)
+}
+
+func TestEnvDatasourceLoadKeyCaseCollision(t *testing.T) {
+	t.Setenv("RENDERKIT_TEST_Foo", "a")
+	t.Setenv("RENDERKIT_TEST_FOO", "b")
+
+	ds := NewEnvDatasourceWithOptions("", EnvOptions{Prefix: "RENDERKIT_TEST_", StripPrefix: true, KeyCase: "lower"})
+	_, err := ds.Load()
+	require.EqualError(t, err, `environment variables "RENDERKIT_TEST_FOO" and "RENDERKIT_TEST_Foo" are both loaded as key "foo"`)
+
+	// Without a key case conversion, the keys are distinct
+	ds = NewEnvDatasourceWithOptions("", EnvOptions{Prefix: "RENDERKIT_TEST_", StripPrefix: true})
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"Foo": "a", "FOO": "b"}, data)
+}
@@ -0,0 +1,119 @@
+package datasources
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type EtcdOptions struct {
+	Endpoint string // etcd HTTP endpoint, e.g. http://127.0.0.1:2379
+	Username string
+	Password string
+}
+
+// EtcdDatasource reads a single key, or all keys under a prefix ending with "/", through the etcd v3 JSON API.
+// The key hierarchy is turned into nested maps, and values that look like JSON or YAML are parsed.
+type EtcdDatasource struct {
+	key     string
+	options EtcdOptions
+	client  *http.Client
+}
+
+func NewEtcdDatasource(key string, options EtcdOptions, client *http.Client) *EtcdDatasource {
+	return &EtcdDatasource{key, options, client}
+}
+
+func (ds *EtcdDatasource) Load() (map[string]any, error) {
+	token := ""
+	if ds.options.Username != "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		if err := ds.request("/v3/auth/authenticate", "", map[string]any{
+			"name":     ds.options.Username,
+			"password": ds.options.Password,
+		}, &auth); err != nil {
+			return nil, fmt.Errorf("authenticate: %s", err)
+		}
+		token = auth.Token
+	}
+
+	rangeRequest := map[string]any{"key": []byte(ds.key)}
+	if strings.HasSuffix(ds.key, "/") {
+		rangeRequest["range_end"] = prefixRangeEnd([]byte(ds.key))
+	}
+
+	var rangeResponse struct {
+		Kvs []struct {
+			Key   []byte `json:"key"` // Base64 encoded in the response
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := ds.request("/v3/kv/range", token, rangeRequest, &rangeResponse); err != nil {
+		return nil, err
+	}
+	if len(rangeResponse.Kvs) == 0 {
+		return nil, fmt.Errorf("key %q not found", ds.key)
+	}
+
+	pairs := make(map[string][]byte, len(rangeResponse.Kvs))
+	for _, kv := range rangeResponse.Kvs {
+		pairs[string(kv.Key)] = kv.Value
+	}
+
+	return kvToNested(ds.key, pairs)
+}
+
+func (ds *EtcdDatasource) request(path string, token string, body any, v any) error {
+	u, err := url.JoinPath(ds.options.Endpoint, path)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	res, err := ds.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var etcdErr struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(res.Body).Decode(&etcdErr) == nil && etcdErr.Message != "" {
+			return fmt.Errorf("unexpected status: %s: %s", res.Status, etcdErr.Message)
+		}
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// prefixRangeEnd returns the end of the key range covering all keys with the given prefix
+func prefixRangeEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0} // All keys
+}
@@ -0,0 +1,81 @@
+package datasources
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdLoad(t *testing.T) {
+	store := map[string]string{
+		"/config/app/name":    "web",
+		"/config/app/db/host": "localhost",
+		"/config/app/db/opts": `{"ssl": true}`,
+		"/config/other":       "other",
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			_, _ = fmt.Fprint(w, `{"token": "etcd-token"}`)
+		case "/v3/kv/range":
+			require.Equal(t, "etcd-token", r.Header.Get("Authorization"))
+			var req struct {
+				Key      []byte `json:"key"`
+				RangeEnd []byte `json:"range_end"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			kvs := []map[string]string{}
+			keys := make([]string, 0, len(store))
+			for k := range store {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				matches := k == string(req.Key)
+				if req.RangeEnd != nil {
+					matches = strings.Compare(k, string(req.Key)) >= 0 && strings.Compare(k, string(req.RangeEnd)) < 0
+				}
+				if matches {
+					kvs = append(kvs, map[string]string{
+						"key":   base64.StdEncoding.EncodeToString([]byte(k)),
+						"value": base64.StdEncoding.EncodeToString([]byte(store[k])),
+					})
+				}
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"kvs": kvs}))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	options := EtcdOptions{Endpoint: ts.URL, Username: "root", Password: "secret"}
+
+	ds := NewEtcdDatasource("/config/app/", options, ts.Client())
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"name": "web",
+		"db": map[string]any{
+			"host": "localhost",
+			"opts": map[string]any{"ssl": true},
+		},
+	}, data)
+
+	ds = NewEtcdDatasource("/config/other", options, ts.Client())
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"other": "other"}, data)
+
+	ds = NewEtcdDatasource("/config/missing", options, ts.Client())
+	_, err = ds.Load()
+	require.ErrorContains(t, err, "not found")
+}
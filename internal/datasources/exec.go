@@ -0,0 +1,79 @@
+package datasources
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type ExecOptions struct {
+	Args    []string
+	Dir     string
+	Env     []string // Extra environment variables (KEY=VALUE) added to the current environment
+	Timeout time.Duration
+}
+
+// ExecDatasource runs a local command and loads its stdout using the datasource returned by parse
+type ExecDatasource struct {
+	command string
+	options ExecOptions
+	parse   func(r io.Reader) (Datasource, error)
+}
+
+func NewExecDatasource(command string, options ExecOptions, parse func(r io.Reader) (Datasource, error)) *ExecDatasource {
+	return &ExecDatasource{command, options, parse}
+}
+
+func (ds *ExecDatasource) Load() (map[string]any, error) {
+	target, err := ds.run()
+	if err != nil {
+		return nil, err
+	}
+	return target.Load()
+}
+
+func (ds *ExecDatasource) LoadValue() (any, error) {
+	target, err := ds.run()
+	if err != nil {
+		return nil, err
+	}
+	if vds, ok := target.(ValueDatasource); ok {
+		return vds.LoadValue()
+	}
+	return target.Load()
+}
+
+func (ds *ExecDatasource) run() (Datasource, error) {
+	ctx := context.Background()
+	if ds.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ds.options.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ds.command, ds.options.Args...)
+	cmd.Dir = ds.options.Dir
+	cmd.Env = append(os.Environ(), ds.options.Env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Don't wait for child processes holding stdout open after the command is killed
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("command %q timed out after %s", ds.command, ds.options.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("run command %q: %s: %s", ds.command, err, msg)
+		}
+		return nil, fmt.Errorf("run command %q: %s", ds.command, err)
+	}
+
+	return ds.parse(&stdout)
+}
@@ -0,0 +1,43 @@
+package datasources
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseJson(r io.Reader) (Datasource, error) {
+	return NewJsonDatasource(r), nil
+}
+
+func TestExecLoad(t *testing.T) {
+	dir := t.TempDir()
+	ds := NewExecDatasource("sh", ExecOptions{
+		Args: []string{"-c", `printf '{"key1": "%s", "dir": "%s"}' "$RENDERKIT_EXEC_VAR" "$(pwd)"`},
+		Dir:  dir,
+		Env:  []string{"RENDERKIT_EXEC_VAR=value1"},
+	}, parseJson)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, "value1", data["key1"])
+	require.NotEmpty(t, data["dir"])
+}
+
+func TestExecLoadFailure(t *testing.T) {
+	ds := NewExecDatasource("sh", ExecOptions{
+		Args: []string{"-c", "echo 'something went wrong' >&2; exit 3"},
+	}, parseJson)
+	data, err := ds.Load()
+	require.ErrorContains(t, err, "something went wrong")
+	require.Nil(t, data)
+
+	ds = NewExecDatasource("sh", ExecOptions{
+		Args:    []string{"-c", "exec sleep 5"},
+		Timeout: 50 * time.Millisecond,
+	}, parseJson)
+	_, err = ds.Load()
+	require.ErrorContains(t, err, "timed out")
+}
@@ -0,0 +1,65 @@
+package datasources
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// GitDatasource reads a file from a local git repository at the given ref without checking it out,
+// and loads its contents using the datasource returned by parse
+type GitDatasource struct {
+	repo  string
+	ref   string
+	path  string
+	parse func(r io.Reader) (Datasource, error)
+}
+
+func NewGitDatasource(repo string, ref string, path string, parse func(r io.Reader) (Datasource, error)) *GitDatasource {
+	return &GitDatasource{repo, ref, path, parse}
+}
+
+func (ds *GitDatasource) Load() (map[string]any, error) {
+	target, err := ds.read()
+	if err != nil {
+		return nil, err
+	}
+	return target.Load()
+}
+
+func (ds *GitDatasource) LoadValue() (any, error) {
+	target, err := ds.read()
+	if err != nil {
+		return nil, err
+	}
+	if vds, ok := target.(ValueDatasource); ok {
+		return vds.LoadValue()
+	}
+	return target.Load()
+}
+
+func (ds *GitDatasource) read() (Datasource, error) {
+	ref := ds.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid ref %q", ref)
+	}
+	path := strings.TrimPrefix(ds.path, "/")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", ds.repo, "cat-file", "blob", ref+":"+path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("read %s at %s from git repository %s: %s", path, ref, ds.repo, msg)
+		}
+		return nil, fmt.Errorf("read %s at %s from git repository %s: %s", path, ref, ds.repo, err)
+	}
+
+	return ds.parse(&stdout)
+}
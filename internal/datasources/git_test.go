@@ -0,0 +1,69 @@
+package datasources
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// newGitRepo creates a repository where values.json is at version 1 in the v1 tag and version 2 at HEAD
+func newGitRepo(t *testing.T) string {
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	for _, version := range []string{"1", "2"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "values.json"), []byte(`{"version": `+version+`}`), os.ModePerm))
+		runGit(t, dir, "add", "values.json")
+		runGit(t, dir, "commit", "-q", "-m", "version "+version)
+		runGit(t, dir, "tag", "v"+version)
+	}
+	// Changes in the working tree are not read
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "values.json"), []byte(`{"version": 3}`), os.ModePerm))
+	return dir
+}
+
+func TestGitLoad(t *testing.T) {
+	repo := newGitRepo(t)
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	runGit(t, repo, "clone", "-q", "--bare", repo, bare)
+
+	for _, dir := range []string{repo, bare} {
+		ds := NewGitDatasource(dir, "", "values.json", parseJson)
+		data, err := ds.Load()
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"version": float64(2)}, data)
+
+		ds = NewGitDatasource(dir, "v1", "/values.json", parseJson)
+		data, err = ds.Load()
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"version": float64(1)}, data)
+	}
+}
+
+func TestGitLoadErrors(t *testing.T) {
+	repo := newGitRepo(t)
+
+	for _, ds := range []*GitDatasource{
+		NewGitDatasource(repo, "v3", "values.json", parseJson),
+		NewGitDatasource(repo, "HEAD", "missing.json", parseJson),
+		NewGitDatasource(repo, "--output=/tmp/x", "values.json", parseJson),
+		NewGitDatasource(t.TempDir(), "", "values.json", parseJson),
+	} {
+		data, err := ds.Load()
+		require.Error(t, err)
+		require.Nil(t, data)
+	}
+}
@@ -0,0 +1,164 @@
+package datasources
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// HclDatasource loads HCL files (such as Terraform .tfvars files) that only contain literal values.
+// Attributes become keys, blocks become nested maps keyed by their type and labels, and repeated
+// unlabeled blocks of the same type become a list. Expressions that need an evaluation context,
+// such as function calls or references to variables, are reported as errors.
+type HclDatasource struct {
+	r        io.Reader
+	filename string
+}
+
+func NewHclDatasource(r io.Reader, filename string) *HclDatasource {
+	return &HclDatasource{r, filename}
+}
+
+func (ds *HclDatasource) Load() (map[string]any, error) {
+	src, err := io.ReadAll(ds.r)
+	if err != nil {
+		return nil, err
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, ds.filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return decodeHclBody(file.Body.(*hclsyntax.Body))
+}
+
+func decodeHclBody(body *hclsyntax.Body) (map[string]any, error) {
+	data := make(map[string]any)
+
+	// Attributes are evaluated in a stable order, so that the same error is reported for the same file
+	names := make([]string, 0, len(body.Attributes))
+	for name := range body.Attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		attr := body.Attributes[name]
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		v, err := ctyToGo(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: attribute %q: %s", attr.SrcRange, name, err)
+		}
+		data[name] = v
+	}
+
+	labeled := make(map[string]bool) // Whether the blocks of each type have labels
+	for _, block := range body.Blocks {
+		blockData, err := decodeHclBody(block.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := body.Attributes[block.Type]; ok {
+			return nil, fmt.Errorf("%s: block %q conflicts with an attribute of the same name", block.DefRange(), block.Type)
+		}
+		if hasLabels, seen := labeled[block.Type]; seen && hasLabels != (len(block.Labels) > 0) {
+			return nil, fmt.Errorf("%s: block type %s used both with and without labels", block.DefRange(), block.Type)
+		}
+		labeled[block.Type] = len(block.Labels) > 0
+
+		if len(block.Labels) == 0 {
+			switch existing := data[block.Type].(type) {
+			case nil:
+				data[block.Type] = blockData
+			case []any:
+				data[block.Type] = append(existing, blockData)
+			case map[string]any:
+				data[block.Type] = []any{existing, blockData}
+			}
+			continue
+		}
+
+		// Labeled blocks are nested under their type and each of their labels
+		parent := data
+		keys := append([]string{block.Type}, block.Labels...)
+		for i, key := range keys {
+			if i == len(keys)-1 {
+				if _, ok := parent[key]; ok {
+					return nil, fmt.Errorf("%s: duplicate block %q", block.DefRange(), key)
+				}
+				parent[key] = blockData
+				break
+			}
+			child, ok := parent[key].(map[string]any)
+			if !ok {
+				if _, exists := parent[key]; exists {
+					return nil, fmt.Errorf("%s: block %q conflicts with another block of the same type", block.DefRange(), block.Type)
+				}
+				child = make(map[string]any)
+				parent[key] = child
+			}
+			parent = child
+		}
+	}
+
+	return data, nil
+}
+
+func ctyToGo(value cty.Value) (any, error) {
+	if value.IsNull() {
+		return nil, nil
+	}
+	if !value.IsKnown() {
+		return nil, fmt.Errorf("value is not known")
+	}
+
+	ty := value.Type()
+	switch {
+	case ty == cty.String:
+		return value.AsString(), nil
+	case ty == cty.Bool:
+		return value.True(), nil
+	case ty == cty.Number:
+		bf := value.AsBigFloat()
+		if bf.IsInt() {
+			if i, acc := bf.Int64(); acc == big.Exact {
+				return i, nil
+			}
+		}
+		f, _ := bf.Float64()
+		return f, nil
+	case ty.IsListType(), ty.IsSetType(), ty.IsTupleType():
+		list := make([]any, 0, value.LengthInt())
+		for it := value.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			v, err := ctyToGo(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case ty.IsMapType(), ty.IsObjectType():
+		m := make(map[string]any, value.LengthInt())
+		for it := value.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			v, err := ctyToGo(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[key.AsString()] = v
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %s", ty.FriendlyName())
+	}
+}
@@ -0,0 +1,97 @@
+package datasources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHclLoad(t *testing.T) {
+	hclData := `
+key1 = "value1"
+key2 = 5
+ratio = 0.5
+enabled = true
+tags = ["a", "b"]
+labels = {
+  team = "platform"
+}
+
+settings {
+  replicas = 3
+}
+
+service "web" {
+  port = 80
+}
+
+service "api" {
+  port = 8080
+}
+
+rule {
+  name = "first"
+}
+
+rule {
+  name = "second"
+}`
+	expectedData := map[string]any{
+		"key1":    "value1",
+		"key2":    int64(5),
+		"ratio":   0.5,
+		"enabled": true,
+		"tags":    []any{"a", "b"},
+		"labels":  map[string]any{"team": "platform"},
+		"settings": map[string]any{
+			"replicas": int64(3),
+		},
+		"service": map[string]any{
+			"web": map[string]any{"port": int64(80)},
+			"api": map[string]any{"port": int64(8080)},
+		},
+		"rule": []any{
+			map[string]any{"name": "first"},
+			map[string]any{"name": "second"},
+		},
+	}
+	r := strings.NewReader(hclData)
+	ds := NewHclDatasource(r, "values.tfvars")
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestHclLoadUnsupportedExpressions(t *testing.T) {
+	ds := NewHclDatasource(strings.NewReader("key1 = \"value1\"\nkey2 = upper(\"value2\")"), "values.tfvars")
+	data, err := ds.Load()
+	require.ErrorContains(t, err, "values.tfvars:2")
+	require.Nil(t, data)
+
+	ds = NewHclDatasource(strings.NewReader("key1 = var.name"), "values.hcl")
+	_, err = ds.Load()
+	require.ErrorContains(t, err, "values.hcl:1")
+}
+
+func TestHclLoadBlocksWithAndWithoutLabels(t *testing.T) {
+	for _, src := range []string{
+		"service {\n  port = 80\n}\nservice \"x\" {\n  port = 81\n}\n",
+		"service \"x\" {\n  port = 81\n}\nservice {\n  port = 80\n}\n",
+	} {
+		ds := NewHclDatasource(strings.NewReader(src), "values.hcl")
+		_, err := ds.Load()
+		require.ErrorContains(t, err, "values.hcl:4,")
+		require.ErrorContains(t, err, "block type service used both with and without labels")
+	}
+}
+
+func TestHclLoadUnsupportedExpressionsOrder(t *testing.T) {
+	// The first invalid attribute by name is reported, whatever the order of the map
+	for range 10 {
+		ds := NewHclDatasource(strings.NewReader("b = var.b\na = var.a\nc = var.c\n"), "values.hcl")
+		_, err := ds.Load()
+		require.ErrorContains(t, err, "values.hcl:2")
+	}
+}
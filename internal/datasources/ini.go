@@ -0,0 +1,135 @@
+package datasources
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// IniDatasource loads INI files. Keys outside of any section are placed at the top level and each
+// section becomes a nested map. Lines starting with ";" or "#" are comments, as is the rest of a line
+// after whitespace followed by ";" or "#" outside of double quotes, a trailing backslash
+// continues a value on the next line, and double-quoted values support backslash escapes.
+type IniDatasource struct {
+	r io.Reader
+}
+
+func NewIniDatasource(r io.Reader) *IniDatasource {
+	return &IniDatasource{r}
+}
+
+func (ds *IniDatasource) Load() (map[string]any, error) {
+	data := make(map[string]any)
+	section := data
+
+	lineNum := 0
+	scanner := bufio.NewScanner(ds.r)
+	for scanner.Scan() {
+		lineNum++
+		line, isComment := stripIniComment(scanner.Text())
+		startLine := lineNum
+		if isComment || line == "" {
+			continue
+		}
+
+		// Join continuation lines, whose comments are stripped line by line
+		for strings.HasSuffix(line, `\`) && scanner.Scan() {
+			lineNum++
+			next, isComment := stripIniComment(scanner.Text())
+			if isComment {
+				continue
+			}
+			line = strings.TrimSuffix(line, `\`) + next
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section header %q", startLine, line)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			existing, ok := data[name]
+			if !ok {
+				section = make(map[string]any)
+				data[name] = section
+				continue
+			}
+			if section, ok = existing.(map[string]any); !ok {
+				return nil, fmt.Errorf("line %d: section %q conflicts with a key of the same name", startLine, name)
+			}
+			continue
+		}
+
+		idx := strings.IndexAny(line, "=:")
+		if idx < 0 {
+			return nil, fmt.Errorf("line %d: expected key=value, got %q", startLine, line)
+		}
+		key := strings.TrimSpace(line[:idx])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", startLine)
+		}
+		value, err := unquoteIniValue(strings.TrimSpace(line[idx+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", startLine, err)
+		}
+		section[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// stripIniComment trims a physical line and removes its trailing comment, which starts with ";" or "#"
+// after whitespace and outside of double quotes. It reports whether the whole line is a comment.
+func stripIniComment(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+		return "", true
+	}
+
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && inQuotes:
+			i++
+		case c == '"':
+			inQuotes = !inQuotes
+		case (c == ';' || c == '#') && !inQuotes && (line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimSpace(line[:i]), false
+		}
+	}
+	return line, false
+}
+
+func unquoteIniValue(value string) (string, error) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value, nil
+	}
+
+	var sb strings.Builder
+	inner := value[1 : len(value)-1]
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(inner) {
+			return "", fmt.Errorf("unterminated escape sequence in %s", value)
+		}
+		switch inner[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(inner[i])
+		}
+	}
+	return sb.String(), nil
+}
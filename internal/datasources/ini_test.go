@@ -0,0 +1,58 @@
+package datasources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIniLoad(t *testing.T) {
+	iniData := `
+; global settings
+name = app
+# database settings
+[database]
+host = localhost
+port: 5432
+hosts = db1, \
+        db2
+replicas = r1, \ ; first replica
+; second replica
+           r2 # last replica
+url = http://example.com/#anchor;x
+quoted = "a ; b" ; comment
+motd = "Hello\tWorld \"quoted\""
+
+[empty]
+`
+	expectedData := map[string]any{
+		"name": "app",
+		"database": map[string]any{
+			"host":     "localhost",
+			"port":     "5432",
+			"hosts":    "db1, db2",
+			"replicas": "r1, r2",
+			"url":      "http://example.com/#anchor;x",
+			"quoted":   "a ; b",
+			"motd":     "Hello\tWorld \"quoted\"",
+		},
+		"empty": map[string]any{},
+	}
+	r := strings.NewReader(iniData)
+	ds := NewIniDatasource(r)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestIniLoadInvalid(t *testing.T) {
+	ds := NewIniDatasource(strings.NewReader("[section\nkey = value"))
+	_, err := ds.Load()
+	require.ErrorContains(t, err, "line 1")
+
+	ds = NewIniDatasource(strings.NewReader("[section]\nkey"))
+	_, err = ds.Load()
+	require.ErrorContains(t, err, "line 2")
+}
@@ -0,0 +1,49 @@
+package datasources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JsonLinesDatasource loads JSON Lines (NDJSON) files as a list of records, one per non-empty line.
+// Since its data is a list, it must be mounted under a key.
+type JsonLinesDatasource struct {
+	r io.Reader
+}
+
+func NewJsonLinesDatasource(r io.Reader) *JsonLinesDatasource {
+	return &JsonLinesDatasource{r}
+}
+
+func (ds *JsonLinesDatasource) Load() (map[string]any, error) {
+	return nil, ErrMountRequired
+}
+
+func (ds *JsonLinesDatasource) LoadValue() (any, error) {
+	records := []any{}
+
+	lineNum := 0
+	reader := bufio.NewReader(ds.r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		lineNum++
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var record any
+			if err := json.Unmarshal(trimmed, &record); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+			records = append(records, record)
+		}
+
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
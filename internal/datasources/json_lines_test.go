@@ -0,0 +1,35 @@
+package datasources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonLinesLoadValue(t *testing.T) {
+	jsonLinesData := `{"name": "web", "port": 80}
+
+{"name": "api", "port": 8080}
+["not", "an", "object"]`
+	expectedData := []any{
+		map[string]any{"name": "web", "port": float64(80)},
+		map[string]any{"name": "api", "port": float64(8080)},
+		[]any{"not", "an", "object"},
+	}
+	ds := NewJsonLinesDatasource(strings.NewReader(jsonLinesData))
+
+	data, err := ds.LoadValue()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	_, err = ds.Load()
+	require.ErrorIs(t, err, ErrMountRequired)
+}
+
+func TestJsonLinesLoadValueInvalid(t *testing.T) {
+	ds := NewJsonLinesDatasource(strings.NewReader("{\"name\": \"web\"}\n{invalid"))
+	data, err := ds.LoadValue()
+	require.ErrorContains(t, err, "line 2")
+	require.Nil(t, data)
+}
@@ -0,0 +1,141 @@
+package datasources
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// K8sDatasource loads ConfigMap and Secret manifests from a (multi-document) YAML stream.
+// ConfigMaps are exposed under "configmaps.<name>" and Secrets under "secrets.<name>", with their
+// base64 encoded data decoded. Other kinds are ignored, and List kinds are expanded into their items.
+// When namespace is set, only manifests in that namespace are loaded, and manifests without one are in "default".
+type K8sDatasource struct {
+	r         io.Reader
+	namespace string
+}
+
+func NewK8sDatasource(r io.Reader, namespace string) *K8sDatasource {
+	return &K8sDatasource{r, namespace}
+}
+
+func (ds *K8sDatasource) Load() (map[string]any, error) {
+	docs, err := NewMultiDocumentYamlDatasource(ds.r, YamlDocumentsList).decodeAll()
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]any{
+		"configmaps": map[string]any{},
+		"secrets":    map[string]any{},
+	}
+	for _, doc := range docs {
+		if err := ds.loadManifest(data, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func (ds *K8sDatasource) loadManifest(data map[string]any, doc any) error {
+	manifest, ok := doc.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	kind, _ := manifest["kind"].(string)
+	metadata, _ := manifest["metadata"].(map[string]any)
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	if namespace == "" {
+		namespace = "default" // Like kubectl, manifests without a namespace are applied to the default namespace
+	}
+
+	var values map[string]any
+	var err error
+	switch kind {
+	case "List", "ConfigMapList", "SecretList":
+		items, _ := manifest["items"].([]any)
+		for _, item := range items {
+			if err := ds.loadManifest(data, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "ConfigMap", "Secret":
+	default:
+		return nil
+	}
+
+	if ds.namespace != "" && namespace != ds.namespace {
+		return nil
+	}
+	if kind == "ConfigMap" {
+		values, err = configMapValues(manifest)
+	} else {
+		values, err = secretValues(manifest)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %q: %s", kind, name, err)
+	}
+	if name == "" {
+		return fmt.Errorf("%s without a name", kind)
+	}
+
+	byName := data["configmaps"].(map[string]any)
+	if kind == "Secret" {
+		byName = data["secrets"].(map[string]any)
+	}
+	if _, ok := byName[name]; ok {
+		return fmt.Errorf("duplicate %s %q, use the namespace option to select one", kind, name)
+	}
+	byName[name] = values
+
+	return nil
+}
+
+// configMapValues returns the ConfigMap's data merged with its base64 decoded binaryData
+func configMapValues(manifest map[string]any) (map[string]any, error) {
+	values := make(map[string]any)
+	if data, ok := manifest["data"].(map[string]any); ok {
+		for k, v := range data {
+			values[k] = v
+		}
+	}
+	binaryData, _ := manifest["binaryData"].(map[string]any)
+	if err := decodeBase64Values(values, binaryData); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// secretValues returns the Secret's base64 decoded data, overridden by its stringData like the API server does
+func secretValues(manifest map[string]any) (map[string]any, error) {
+	values := make(map[string]any)
+	data, _ := manifest["data"].(map[string]any)
+	if err := decodeBase64Values(values, data); err != nil {
+		return nil, err
+	}
+	if stringData, ok := manifest["stringData"].(map[string]any); ok {
+		for k, v := range stringData {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+func decodeBase64Values(values map[string]any, encoded map[string]any) error {
+	for k, v := range encoded {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("value of key %q is not a base64 string", k)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("decode key %q: %s", k, err)
+		}
+		values[k] = string(decoded)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package datasources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const k8sManifests = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: default
+data:
+  LOG_LEVEL: debug
+  config.yaml: |
+    port: 8080
+binaryData:
+  logo.txt: aGVsbG8=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db-credentials
+type: Opaque
+data:
+  username: YWRtaW4=
+  password: aHVudGVyMg==
+stringData:
+  password: override
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: other-config
+      namespace: staging
+    data:
+      KEY: value
+`
+
+func TestK8sLoad(t *testing.T) {
+	ds := NewK8sDatasource(strings.NewReader(k8sManifests), "")
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"configmaps": map[string]any{
+			"app-config": map[string]any{
+				"LOG_LEVEL":   "debug",
+				"config.yaml": "port: 8080\n",
+				"logo.txt":    "hello",
+			},
+			"other-config": map[string]any{"KEY": "value"},
+		},
+		"secrets": map[string]any{
+			"db-credentials": map[string]any{"username": "admin", "password": "override"},
+		},
+	}, data)
+
+	ds = NewK8sDatasource(strings.NewReader(k8sManifests), "staging")
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"configmaps": map[string]any{"other-config": map[string]any{"KEY": "value"}},
+		"secrets":    map[string]any{},
+	}, data)
+
+	// Manifests without a namespace are in the default namespace
+	ds = NewK8sDatasource(strings.NewReader(k8sManifests), "default")
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"configmaps": map[string]any{
+			"app-config": map[string]any{
+				"LOG_LEVEL":   "debug",
+				"config.yaml": "port: 8080\n",
+				"logo.txt":    "hello",
+			},
+		},
+		"secrets": map[string]any{
+			"db-credentials": map[string]any{"username": "admin", "password": "override"},
+		},
+	}, data)
+}
+
+func TestK8sLoadErrors(t *testing.T) {
+	for _, manifests := range []string{
+		"kind: Secret\nmetadata:\n  name: invalid\ndata:\n  key: not base64!",
+		"kind: ConfigMap\ndata:\n  key: value",
+		"kind: ConfigMap\nmetadata:\n  name: dup\n---\nkind: ConfigMap\nmetadata:\n  name: dup",
+		"kind: ConfigMap\n  invalid: yaml",
+	} {
+		ds := NewK8sDatasource(strings.NewReader(manifests), "")
+		data, err := ds.Load()
+		require.Error(t, err)
+		require.Nil(t, data)
+	}
+}
@@ -0,0 +1,66 @@
+package datasources
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kvToNested turns key/value pairs from a key/value store into nested maps. Keys are made relative to
+// prefix and split on "/". When prefix is a single key rather than a directory (no trailing "/"), the
+// key's last segment is used.
+func kvToNested(prefix string, pairs map[string][]byte) (map[string]any, error) {
+	data := make(map[string]any)
+
+	base := prefix
+	if !strings.HasSuffix(prefix, "/") {
+		base = prefix[:strings.LastIndex(prefix, "/")+1]
+	}
+
+	for key, value := range pairs {
+		var path []string
+		for _, segment := range strings.Split(strings.TrimPrefix(key, base), "/") {
+			if segment != "" {
+				path = append(path, segment)
+			}
+		}
+		if len(path) == 0 {
+			continue
+		}
+		if err := setNestedValue(data, path, parseKVValue(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// parseKVValue parses values that are JSON (or YAML flow) objects and lists, or YAML documents starting with ---,
+// and returns other values, such as "Error: disk full", as strings
+func parseKVValue(value []byte) any {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return string(value)
+	}
+
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		var v any
+		if err := json.Unmarshal(trimmed, &v); err == nil {
+			return v
+		}
+	}
+
+	if trimmed[0] == '{' || trimmed[0] == '[' || bytes.HasPrefix(trimmed, []byte("---")) {
+		var v any
+		if err := yaml.Unmarshal(trimmed, &v); err == nil {
+			switch v.(type) {
+			case map[string]any, []any:
+				return v
+			}
+		}
+	}
+
+	return string(value)
+}
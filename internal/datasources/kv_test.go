@@ -0,0 +1,46 @@
+package datasources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVToNested(t *testing.T) {
+	data, err := kvToNested("config/app/", map[string][]byte{
+		"config/app/":        nil,
+		"config/app/name":    []byte("web"),
+		"config/app/db/host": []byte("localhost"),
+		"config/app/db/port": []byte("5432"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"name": "web",
+		"db":   map[string]any{"host": "localhost", "port": "5432"},
+	}, data)
+
+	data, err = kvToNested("config/app/name", map[string][]byte{"config/app/name": []byte("web")})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"name": "web"}, data)
+
+	_, err = kvToNested("config/", map[string][]byte{
+		"config/db":      []byte("localhost"),
+		"config/db/port": []byte("5432"),
+	})
+	require.Error(t, err)
+}
+
+func TestParseKVValue(t *testing.T) {
+	require.Equal(t, "plain", parseKVValue([]byte("plain")))
+	require.Equal(t, "", parseKVValue([]byte("")))
+	require.Equal(t, "port: 5432", parseKVValue([]byte("port: 5432")))
+	require.Equal(t, "Error: disk full", parseKVValue([]byte("Error: disk full")))
+	require.Equal(t, "note: see docs\nagain", parseKVValue([]byte("note: see docs\nagain")))
+	require.Equal(t, map[string]any{"port": 5432}, parseKVValue([]byte("---\nport: 5432")))
+	require.Equal(t, map[string]any{"port": 5432}, parseKVValue([]byte("{port: 5432}")))
+	require.Equal(t, "{not json", parseKVValue([]byte("{not json")))
+	require.Equal(t, map[string]any{"a": float64(1)}, parseKVValue([]byte(`{"a": 1}`)))
+	require.Equal(t, []any{"a", "b"}, parseKVValue([]byte("---\n- a\n- b")))
+	require.Equal(t, "- a\n- b", parseKVValue([]byte("- a\n- b")))
+	require.Equal(t, "line one\nline two", parseKVValue([]byte("line one\nline two")))
+}
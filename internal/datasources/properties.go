@@ -0,0 +1,142 @@
+package datasources
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PropertiesDatasource loads Java .properties files. Lines starting with "#" or "!" are comments,
+// keys are separated from values by "=", ":" or whitespace, a trailing backslash continues a value
+// on the next line, and the standard escapes (including \uXXXX) are supported.
+// When nested is set, dotted keys such as "db.host" become nested maps.
+type PropertiesDatasource struct {
+	r      io.Reader
+	nested bool
+}
+
+func NewPropertiesDatasource(r io.Reader, nested bool) *PropertiesDatasource {
+	return &PropertiesDatasource{r, nested}
+}
+
+func (ds *PropertiesDatasource) Load() (map[string]any, error) {
+	data := make(map[string]any)
+
+	lineNum := 0
+	scanner := bufio.NewScanner(ds.r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		startLine := lineNum
+
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// Join continuation lines. A line is continued when it ends with an odd number of backslashes.
+		for endsWithContinuation(line) && scanner.Scan() {
+			lineNum++
+			line = line[:len(line)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+
+		rawKey, rawValue := splitProperty(line)
+		key, err := unescapeProperty(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", startLine, err)
+		}
+		value, err := unescapeProperty(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", startLine, err)
+		}
+
+		if !ds.nested {
+			data[key] = value
+			continue
+		}
+		if err := setNestedValue(data, strings.Split(key, "."), value); err != nil {
+			return nil, fmt.Errorf("line %d: %s", startLine, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func endsWithContinuation(line string) bool {
+	backslashes := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// splitProperty splits a logical line into its raw key and value, leaving escapes untouched
+func splitProperty(line string) (string, string) {
+	i := 0
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+	}
+	if i >= len(line) {
+		return line, ""
+	}
+	key := line[:i]
+
+	rest := strings.TrimLeft(line[i:], " \t\f")
+	if len(rest) > 0 && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(s) {
+			break
+		}
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(s) {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			sb.WriteRune(rune(r))
+			i += 4
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
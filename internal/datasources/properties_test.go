@@ -0,0 +1,62 @@
+package datasources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const propertiesData = `
+# comment
+! another comment
+db.host = localhost
+db.port:5432
+app.name   My\ App
+app.greeting = Hello, \
+    World
+app.path = C:\\temp
+app.unicode = caf\u00e9
+`
+
+func TestPropertiesLoad(t *testing.T) {
+	expectedData := map[string]any{
+		"db.host":      "localhost",
+		"db.port":      "5432",
+		"app.name":     "My App",
+		"app.greeting": "Hello, World",
+		"app.path":     `C:\temp`,
+		"app.unicode":  "café",
+	}
+	r := strings.NewReader(propertiesData)
+	ds := NewPropertiesDatasource(r, false)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestPropertiesLoadNested(t *testing.T) {
+	expectedData := map[string]any{
+		"db": map[string]any{
+			"host": "localhost",
+			"port": "5432",
+		},
+		"app": map[string]any{
+			"name":     "My App",
+			"greeting": "Hello, World",
+			"path":     `C:\temp`,
+			"unicode":  "café",
+		},
+	}
+	r := strings.NewReader(propertiesData)
+	ds := NewPropertiesDatasource(r, true)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	ds = NewPropertiesDatasource(strings.NewReader("db=x\ndb.host=localhost"), true)
+	_, err = ds.Load()
+	require.ErrorContains(t, err, "line 2")
+}
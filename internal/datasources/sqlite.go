@@ -0,0 +1,123 @@
+package datasources
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	_ "modernc.org/sqlite"
+)
+
+// readOnlyStatement matches the statements a SqliteDatasource may run
+var readOnlyStatement = regexp.MustCompile(`(?is)^\s*(select|with|values)\b`)
+
+// SqliteDatasource runs a read-only query against a SQLite database and loads the result rows as maps of column names to values.
+// The rows are loaded as a list, which must be mounted under a key, or as a map keyed by the value of keyColumn when it is set.
+// The database is opened in read-only mode, and statements other than queries are rejected.
+type SqliteDatasource struct {
+	path      string
+	query     string
+	keyColumn string
+}
+
+func NewSqliteDatasource(path string, query string, keyColumn string) *SqliteDatasource {
+	return &SqliteDatasource{path, query, keyColumn}
+}
+
+func (ds *SqliteDatasource) Load() (map[string]any, error) {
+	if ds.keyColumn == "" {
+		return nil, ErrMountRequired
+	}
+
+	rows, err := ds.queryRows()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]any, len(rows))
+	for _, row := range rows {
+		value, ok := row[ds.keyColumn]
+		if !ok {
+			return nil, fmt.Errorf("key column %q not found in query result", ds.keyColumn)
+		}
+		key := fmt.Sprint(value)
+		if _, ok := data[key]; ok {
+			return nil, fmt.Errorf("duplicate value %q in key column %q", key, ds.keyColumn)
+		}
+		data[key] = row
+	}
+
+	return data, nil
+}
+
+func (ds *SqliteDatasource) LoadValue() (any, error) {
+	if ds.keyColumn != "" {
+		return ds.Load()
+	}
+
+	rows, err := ds.queryRows()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]any, len(rows))
+	for i, row := range rows {
+		list[i] = row
+	}
+	return list, nil
+}
+
+func (ds *SqliteDatasource) queryRows() ([]map[string]any, error) {
+	if ds.query == "" {
+		return nil, errors.New("query is required")
+	}
+	if !readOnlyStatement.MatchString(ds.query) {
+		return nil, errors.New("only read-only queries (SELECT, WITH or VALUES) are allowed")
+	}
+
+	// The database is opened read-only, and query_only rejects writes that would go through anyway (e.g. in a CTE)
+	dsn := "file:" + (&url.URL{Path: ds.path}).EscapedPath() + "?mode=ro&_pragma=query_only(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(ds.query)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %s", ds.path, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query %s: %s", ds.path, err)
+	}
+
+	return result, nil
+}
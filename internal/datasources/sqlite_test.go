@@ -0,0 +1,68 @@
+package datasources
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newSqliteDatabase(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "inventory.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE hosts (name TEXT, ip TEXT, cpus INTEGER, load REAL, notes BLOB);
+		INSERT INTO hosts VALUES ('web', '10.0.0.1', 4, 0.5, X'6869'), ('db', '10.0.0.2', 8, 1.5, NULL);
+	`)
+	require.NoError(t, err)
+	return path
+}
+
+func TestSqliteLoad(t *testing.T) {
+	path := newSqliteDatabase(t)
+
+	ds := NewSqliteDatasource(path, "SELECT name, cpus, load, notes FROM hosts ORDER BY name", "")
+	_, err := ds.Load()
+	require.ErrorIs(t, err, ErrMountRequired)
+	value, err := ds.LoadValue()
+	require.NoError(t, err)
+	require.Equal(t, []any{
+		map[string]any{"name": "db", "cpus": int64(8), "load": 1.5, "notes": nil},
+		map[string]any{"name": "web", "cpus": int64(4), "load": 0.5, "notes": "hi"},
+	}, value)
+
+	ds = NewSqliteDatasource(path, "select name, ip from hosts", "name")
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"web": map[string]any{"name": "web", "ip": "10.0.0.1"},
+		"db":  map[string]any{"name": "db", "ip": "10.0.0.2"},
+	}, data)
+}
+
+func TestSqliteLoadErrors(t *testing.T) {
+	path := newSqliteDatabase(t)
+
+	for _, ds := range []*SqliteDatasource{
+		NewSqliteDatasource(path, "", ""),
+		NewSqliteDatasource(path, "DELETE FROM hosts", ""),
+		NewSqliteDatasource(path, "WITH x AS (SELECT 1) DELETE FROM hosts", ""),
+		NewSqliteDatasource(path, "SELECT * FROM missing", ""),
+		NewSqliteDatasource(path, "SELECT name FROM hosts", "ip"),
+		NewSqliteDatasource(path, "SELECT 'same' AS name FROM hosts", "name"),
+		NewSqliteDatasource(filepath.Join(t.TempDir(), "missing.db"), "SELECT 1", ""),
+	} {
+		value, err := ds.LoadValue()
+		require.Error(t, err)
+		require.Nil(t, value)
+	}
+
+	// The database is left untouched
+	value, err := NewSqliteDatasource(path, "SELECT count(*) AS count FROM hosts", "").LoadValue()
+	require.NoError(t, err)
+	require.Equal(t, []any{map[string]any{"count": int64(2)}}, value)
+}
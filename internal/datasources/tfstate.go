@@ -0,0 +1,156 @@
+package datasources
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TfstateDatasource loads a Terraform state file or the output of `terraform output -json`.
+// Outputs are exposed under "outputs", managed resources under "resources.<type>.<name>" and
+// data sources under "data.<type>.<name>". Resources that belong to a module are nested under
+// "modules.<module address>" using the same layout. Resources with count or for_each are keyed
+// by their index key. Sensitive outputs and attributes are excluded unless includeSensitive is set.
+type TfstateDatasource struct {
+	r                io.Reader
+	includeSensitive bool
+}
+
+func NewTfstateDatasource(r io.Reader, includeSensitive bool) *TfstateDatasource {
+	return &TfstateDatasource{r, includeSensitive}
+}
+
+func (ds *TfstateDatasource) Load() (map[string]any, error) {
+	raw, err := NewJsonDatasource(ds.r).Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if isTerraformState(raw) {
+		return ds.loadState(raw)
+	}
+
+	// Otherwise, expect the output of `terraform output -json`
+	outputs, err := ds.loadOutputs(raw)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"outputs": outputs}, nil
+}
+
+// isTerraformState reports whether the document is a state file rather than the output of `terraform output -json`,
+// whose top-level keys are output names and may include any of the state's keys
+func isTerraformState(raw map[string]any) bool {
+	if _, ok := raw["terraform_version"].(string); !ok {
+		return false
+	}
+	if _, ok := raw["resources"].([]any); ok {
+		return true
+	}
+	_, hasVersion := raw["version"].(float64)
+	_, hasSerial := raw["serial"].(float64)
+	_, hasLineage := raw["lineage"].(string)
+	return hasVersion && hasSerial && hasLineage
+}
+
+func (ds *TfstateDatasource) loadState(raw map[string]any) (map[string]any, error) {
+	rawOutputs, _ := raw["outputs"].(map[string]any)
+	outputs, err := ds.loadOutputs(rawOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]any{
+		"outputs":   outputs,
+		"resources": map[string]any{},
+		"data":      map[string]any{},
+	}
+
+	rawResources, _ := raw["resources"].([]any)
+	for _, r := range rawResources {
+		resource, ok := r.(map[string]any)
+		if !ok {
+			return nil, errors.New("invalid resource in state")
+		}
+		mode, _ := resource["mode"].(string)
+		resourceType, _ := resource["type"].(string)
+		name, _ := resource["name"].(string)
+		module, _ := resource["module"].(string)
+
+		root := data
+		if module != "" {
+			modules := ensureMap(data, "modules")
+			root = ensureMap(modules, module)
+		}
+
+		modeKey := "resources"
+		if mode == "data" {
+			modeKey = "data"
+		}
+		byType := ensureMap(ensureMap(root, modeKey), resourceType)
+
+		instances, _ := resource["instances"].([]any)
+		for _, i := range instances {
+			instance, ok := i.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid instance of resource %s.%s", resourceType, name)
+			}
+			attributes, _ := instance["attributes"].(map[string]any)
+			if !ds.includeSensitive {
+				removeSensitiveAttributes(attributes, instance["sensitive_attributes"])
+			}
+
+			indexKey, ok := instance["index_key"]
+			if !ok {
+				byType[name] = attributes
+				continue
+			}
+			ensureMap(byType, name)[fmt.Sprint(indexKey)] = attributes
+		}
+	}
+
+	return data, nil
+}
+
+func (ds *TfstateDatasource) loadOutputs(raw map[string]any) (map[string]any, error) {
+	outputs := make(map[string]any)
+	for name, o := range raw {
+		output, ok := o.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid output %q: expected an object", name)
+		}
+		value, ok := output["value"]
+		if !ok {
+			return nil, fmt.Errorf("invalid output %q: missing value", name)
+		}
+		if sensitive, _ := output["sensitive"].(bool); sensitive && !ds.includeSensitive {
+			continue
+		}
+		outputs[name] = value
+	}
+	return outputs, nil
+}
+
+// removeSensitiveAttributes removes the top-level attributes referenced by the state's sensitive attribute paths
+func removeSensitiveAttributes(attributes map[string]any, sensitivePaths any) {
+	paths, _ := sensitivePaths.([]any)
+	for _, p := range paths {
+		steps, _ := p.([]any)
+		if len(steps) == 0 {
+			continue
+		}
+		step, _ := steps[0].(map[string]any)
+		if attr, ok := step["value"].(string); ok && step["type"] == "get_attr" {
+			delete(attributes, attr)
+		}
+	}
+}
+
+func ensureMap(parent map[string]any, key string) map[string]any {
+	if m, ok := parent[key].(map[string]any); ok {
+		return m
+	}
+	m := make(map[string]any)
+	parent[key] = m
+	return m
+}
@@ -0,0 +1,127 @@
+package datasources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const tfstateData = `
+{
+	"version": 4,
+	"terraform_version": "1.9.0",
+	"outputs": {
+		"endpoint": {"value": "db.example.com", "type": "string"},
+		"password": {"value": "hunter2", "type": "string", "sensitive": true}
+	},
+	"resources": [
+		{
+			"mode": "managed",
+			"type": "aws_db_instance",
+			"name": "main",
+			"instances": [
+				{
+					"attributes": {"port": 5432, "password": "hunter2"},
+					"sensitive_attributes": [[{"type": "get_attr", "value": "password"}]]
+				}
+			]
+		},
+		{
+			"mode": "data",
+			"type": "aws_region",
+			"name": "current",
+			"instances": [{"attributes": {"name": "eu-west-1"}}]
+		},
+		{
+			"module": "module.network",
+			"mode": "managed",
+			"type": "aws_subnet",
+			"name": "private",
+			"instances": [
+				{"index_key": 0, "attributes": {"cidr_block": "10.0.1.0/24"}},
+				{"index_key": 1, "attributes": {"cidr_block": "10.0.2.0/24"}}
+			]
+		}
+	]
+}`
+
+func TestTfstateLoad(t *testing.T) {
+	expectedData := map[string]any{
+		"outputs": map[string]any{
+			"endpoint": "db.example.com",
+		},
+		"resources": map[string]any{
+			"aws_db_instance": map[string]any{
+				"main": map[string]any{"port": float64(5432)},
+			},
+		},
+		"data": map[string]any{
+			"aws_region": map[string]any{
+				"current": map[string]any{"name": "eu-west-1"},
+			},
+		},
+		"modules": map[string]any{
+			"module.network": map[string]any{
+				"resources": map[string]any{
+					"aws_subnet": map[string]any{
+						"private": map[string]any{
+							"0": map[string]any{"cidr_block": "10.0.1.0/24"},
+							"1": map[string]any{"cidr_block": "10.0.2.0/24"},
+						},
+					},
+				},
+			},
+		},
+	}
+	ds := NewTfstateDatasource(strings.NewReader(tfstateData), false)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestTfstateLoadSensitive(t *testing.T) {
+	ds := NewTfstateDatasource(strings.NewReader(tfstateData), true)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", data["outputs"].(map[string]any)["password"])
+	require.Equal(t, "hunter2", data["resources"].(map[string]any)["aws_db_instance"].(map[string]any)["main"].(map[string]any)["password"])
+}
+
+func TestTfstateLoadOutputJson(t *testing.T) {
+	outputData := `
+{
+	"endpoint": {"sensitive": false, "type": "string", "value": "db.example.com"},
+	"password": {"sensitive": true, "type": "string", "value": "hunter2"}
+}`
+	expectedData := map[string]any{
+		"outputs": map[string]any{
+			"endpoint": "db.example.com",
+		},
+	}
+	ds := NewTfstateDatasource(strings.NewReader(outputData), false)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	ds = NewTfstateDatasource(strings.NewReader(`{"key1": "value1"}`), false)
+	_, err = ds.Load()
+	require.Error(t, err)
+
+	// Outputs named like the keys of a state file are still outputs
+	outputData = `{"terraform_version": {"type": "string", "value": "1.9.0"}, "version": {"type": "number", "value": 4}}`
+	ds = NewTfstateDatasource(strings.NewReader(outputData), false)
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"outputs": map[string]any{"terraform_version": "1.9.0", "version": float64(4)}}, data)
+}
+
+func TestTfstateLoadStateWithoutResources(t *testing.T) {
+	ds := NewTfstateDatasource(strings.NewReader(`{"version": 4, "terraform_version": "1.9.0", "serial": 1, "lineage": "8d0e", "outputs": {"name": {"value": "app"}}}`), false)
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"outputs": map[string]any{"name": "app"}, "resources": map[string]any{}, "data": map[string]any{}}, data)
+}
@@ -0,0 +1,175 @@
+package datasources
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type VaultOptions struct {
+	Address      string // Vault server address, e.g. https://vault.example.com:8200
+	Token        string
+	RoleID       string // AppRole role ID, used when no token is set
+	SecretID     string // AppRole secret ID, used when no token is set
+	AppRoleMount string // Mount path of the AppRole auth method, "approle" by default
+	Namespace    string
+	KVVersion    int // KV secrets engine version (1 or 2), detected from the mount when 0
+}
+
+// VaultDatasource reads a secret from a KV v1 or v2 secrets engine through the Vault HTTP API.
+// The secret's data is loaded as is, or only the given key when one is set.
+type VaultDatasource struct {
+	mount   string
+	path    string
+	key     string
+	options VaultOptions
+	client  *http.Client
+}
+
+func NewVaultDatasource(mount string, path string, key string, options VaultOptions, client *http.Client) *VaultDatasource {
+	return &VaultDatasource{mount, path, key, options, client}
+}
+
+func (ds *VaultDatasource) Load() (map[string]any, error) {
+	if ds.options.Address == "" {
+		return nil, errors.New("vault address is required")
+	}
+
+	token := ds.options.Token
+	if token == "" {
+		var err error
+		if token, err = ds.loginAppRole(); err != nil {
+			return nil, fmt.Errorf("login with AppRole: %s", err)
+		}
+	}
+
+	version := ds.options.KVVersion
+	if version == 0 {
+		version = ds.detectKVVersion(token)
+	}
+
+	var secretPath string
+	switch version {
+	case 1:
+		secretPath = ds.mount + "/" + ds.path
+	case 2:
+		secretPath = ds.mount + "/data/" + ds.path
+	default:
+		return nil, fmt.Errorf("unsupported KV version: %d", version)
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := ds.request(http.MethodGet, secretPath, token, nil, &secret); err != nil {
+		return nil, fmt.Errorf("read secret %s/%s: %s", ds.mount, ds.path, err)
+	}
+
+	data := secret.Data
+	if version == 2 {
+		data, _ = secret.Data["data"].(map[string]any)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("secret %s/%s has no data", ds.mount, ds.path)
+	}
+
+	if ds.key == "" {
+		return data, nil
+	}
+	value, ok := data[ds.key]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found in secret %s/%s", ds.key, ds.mount, ds.path)
+	}
+	return map[string]any{ds.key: value}, nil
+}
+
+func (ds *VaultDatasource) loginAppRole() (string, error) {
+	if ds.options.RoleID == "" || ds.options.SecretID == "" {
+		return "", errors.New("a token or an AppRole role ID and secret ID are required")
+	}
+
+	mount := ds.options.AppRoleMount
+	if mount == "" {
+		mount = "approle"
+	}
+	body, err := json.Marshal(map[string]string{
+		"role_id":   ds.options.RoleID,
+		"secret_id": ds.options.SecretID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := ds.request(http.MethodPost, "auth/"+mount+"/login", "", body, &login); err != nil {
+		return "", err
+	}
+	if login.Auth.ClientToken == "" {
+		return "", errors.New("no client token in response")
+	}
+	return login.Auth.ClientToken, nil
+}
+
+// detectKVVersion looks up the version of the mount's KV secrets engine, assuming version 2 if it cannot be determined
+func (ds *VaultDatasource) detectKVVersion(token string) int {
+	var mount struct {
+		Data struct {
+			Options struct {
+				Version string `json:"version"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	if err := ds.request(http.MethodGet, "sys/internal/ui/mounts/"+ds.mount, token, nil, &mount); err != nil {
+		return 2
+	}
+	if mount.Data.Options.Version == "1" {
+		return 1
+	}
+	return 2
+}
+
+func (ds *VaultDatasource) request(method string, path string, token string, body []byte, v any) error {
+	u, err := url.JoinPath(strings.TrimSuffix(ds.options.Address, "/"), "v1", path)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if ds.options.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", ds.options.Namespace)
+	}
+
+	res, err := ds.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		msg, _ := io.ReadAll(res.Body)
+		if json.Unmarshal(msg, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("unexpected status: %s: %s", res.Status, strings.Join(vaultErr.Errors, ", "))
+		}
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
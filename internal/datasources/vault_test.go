@@ -0,0 +1,104 @@
+package datasources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeVault starts a server implementing the parts of the Vault HTTP API used by VaultDatasource,
+// with a KV v2 engine mounted at "secret" and a KV v1 engine mounted at "kv"
+func newFakeVault(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/approle/login" {
+			var login map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&login))
+			if login["role_id"] != "role" || login["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `{"errors": ["invalid role or secret ID"]}`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"auth": {"client_token": "approle-token"}}`)
+			return
+		}
+
+		token := r.Header.Get("X-Vault-Token")
+		if token != "root-token" && token != "approle-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/sys/internal/ui/mounts/secret":
+			_, _ = fmt.Fprint(w, `{"data": {"path": "secret/", "options": {"version": "2"}}}`)
+		case "/v1/sys/internal/ui/mounts/kv":
+			_, _ = fmt.Fprint(w, `{"data": {"path": "kv/", "options": {"version": "1"}}}`)
+		case "/v1/secret/data/myapp/config":
+			_, _ = fmt.Fprint(w, `{"data": {"data": {"username": "admin", "password": "hunter2"}, "metadata": {"version": 3}}}`)
+		case "/v1/kv/myapp/config":
+			_, _ = fmt.Fprint(w, `{"data": {"username": "legacy"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"errors": []}`)
+		}
+	}))
+}
+
+func TestVaultLoad(t *testing.T) {
+	ts := newFakeVault(t)
+	defer ts.Close()
+
+	// KV v2
+	ds := NewVaultDatasource("secret", "myapp/config", "", VaultOptions{Address: ts.URL, Token: "root-token"}, ts.Client())
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"username": "admin", "password": "hunter2"}, data)
+
+	// KV v1
+	ds = NewVaultDatasource("kv", "myapp/config", "", VaultOptions{Address: ts.URL, Token: "root-token"}, ts.Client())
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"username": "legacy"}, data)
+
+	// Single key
+	ds = NewVaultDatasource("secret", "myapp/config", "password", VaultOptions{Address: ts.URL, Token: "root-token", KVVersion: 2}, ts.Client())
+	data, err = ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"password": "hunter2"}, data)
+}
+
+func TestVaultLoadAppRole(t *testing.T) {
+	ts := newFakeVault(t)
+	defer ts.Close()
+
+	ds := NewVaultDatasource("secret", "myapp/config", "username", VaultOptions{Address: ts.URL, RoleID: "role", SecretID: "secret"}, ts.Client())
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"username": "admin"}, data)
+
+	ds = NewVaultDatasource("secret", "myapp/config", "", VaultOptions{Address: ts.URL, RoleID: "role", SecretID: "wrong"}, ts.Client())
+	_, err = ds.Load()
+	require.ErrorContains(t, err, "invalid role or secret ID")
+}
+
+func TestVaultLoadErrors(t *testing.T) {
+	ts := newFakeVault(t)
+	defer ts.Close()
+
+	for _, ds := range []*VaultDatasource{
+		NewVaultDatasource("secret", "myapp/config", "", VaultOptions{Token: "root-token"}, ts.Client()),
+		NewVaultDatasource("secret", "myapp/config", "", VaultOptions{Address: ts.URL}, ts.Client()),
+		NewVaultDatasource("secret", "myapp/config", "", VaultOptions{Address: ts.URL, Token: "wrong"}, ts.Client()),
+		NewVaultDatasource("secret", "myapp/missing", "", VaultOptions{Address: ts.URL, Token: "root-token"}, ts.Client()),
+		NewVaultDatasource("secret", "myapp/config", "missing", VaultOptions{Address: ts.URL, Token: "root-token"}, ts.Client()),
+	} {
+		data, err := ds.Load()
+		require.Error(t, err)
+		require.Nil(t, data)
+	}
+}
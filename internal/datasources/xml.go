@@ -0,0 +1,99 @@
+package datasources
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	xmlAttributePrefix = "@"
+	xmlTextKey         = "#text"
+)
+
+// XmlDatasource converts an XML document into nested maps using the following convention:
+//   - The root element becomes the single top-level key.
+//   - Attributes are stored as keys prefixed with "@" (e.g. "@id").
+//   - Elements without attributes or child elements become their text content as a string.
+//   - Text content of elements that also have attributes or child elements is stored under "#text".
+//   - Sibling elements sharing the same name become a list.
+//
+// Namespace prefixes are dropped and only local names are used.
+type XmlDatasource struct {
+	r io.Reader
+}
+
+func NewXmlDatasource(r io.Reader) *XmlDatasource {
+	return &XmlDatasource{r}
+}
+
+func (ds *XmlDatasource) Load() (map[string]any, error) {
+	decoder := xml.NewDecoder(ds.r)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, errors.New("no root element found")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := decodeXmlElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{start.Name.Local: value}, nil
+		}
+	}
+}
+
+func decodeXmlElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	element := make(map[string]any)
+	var text strings.Builder
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		element[xmlAttributePrefix+attr.Name.Local] = attr.Value
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("decode element %q: %s", start.Name.Local, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXmlElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := element[name].(type) {
+			case nil:
+				element[name] = child
+			case []any:
+				element[name] = append(existing, child)
+			default:
+				element[name] = []any{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			trimmed := strings.TrimSpace(text.String())
+			if len(element) == 0 {
+				return trimmed, nil
+			}
+			if trimmed != "" {
+				element[xmlTextKey] = trimmed
+			}
+			return element, nil
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package datasources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestXmlLoad(t *testing.T) {
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<config env="prod">
+	<key1>value1</key1>
+	<key2>5</key2>
+	<empty/>
+	<server name="a">primary</server>
+	<item>first</item>
+	<item>second</item>
+</config>`
+	expectedData := map[string]any{
+		"config": map[string]any{
+			"@env":  "prod",
+			"key1":  "value1",
+			"key2":  "5",
+			"empty": "",
+			"server": map[string]any{
+				"@name": "a",
+				"#text": "primary",
+			},
+			"item": []any{"first", "second"},
+		},
+	}
+	r := strings.NewReader(xmlData)
+	ds := NewXmlDatasource(r)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestXmlLoadInvalid(t *testing.T) {
+	ds := NewXmlDatasource(strings.NewReader("<config><key1>value1</config>"))
+	data, err := ds.Load()
+	require.Error(t, err)
+	require.Nil(t, data)
+
+	ds = NewXmlDatasource(strings.NewReader(""))
+	_, err = ds.Load()
+	require.Error(t, err)
+}
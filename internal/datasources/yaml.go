@@ -1,24 +1,121 @@
 package datasources
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	"gopkg.in/yaml.v3"
 )
 
+// YamlDocuments controls how a YAML stream with multiple documents is loaded
+type YamlDocuments string
+
+const (
+	YamlDocumentsSingle YamlDocuments = ""      // The stream must have a single non-empty document
+	YamlDocumentsFirst  YamlDocuments = "first" // Only the first document is loaded, and the other ones are ignored
+	YamlDocumentsMerge  YamlDocuments = "merge" // Documents are merged in order, later documents override earlier keys
+	YamlDocumentsList   YamlDocuments = "list"  // Documents are loaded as a list, which requires mounting under a key
+)
+
+var ErrMultipleDocuments = errors.New("YAML stream has more than one document, use the documents option (first, merge or list) to choose how to load them")
+
 type YamlDatasource struct {
-	r io.Reader
+	r         io.Reader
+	documents YamlDocuments
 }
 
 func NewYamlDatasource(r io.Reader) *YamlDatasource {
-	return &YamlDatasource{r}
+	return &YamlDatasource{r, YamlDocumentsSingle}
+}
+
+func NewMultiDocumentYamlDatasource(r io.Reader, documents YamlDocuments) *YamlDatasource {
+	return &YamlDatasource{r, documents}
 }
 
 func (ds *YamlDatasource) Load() (map[string]any, error) {
-	data := make(map[string]any)
+	switch ds.documents {
+	case YamlDocumentsSingle, YamlDocumentsFirst:
+		data := make(map[string]any)
+		if err := ds.decodeFirst(&data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	case YamlDocumentsMerge:
+		docs, err := ds.decodeAll()
+		if err != nil {
+			return nil, err
+		}
+		data := make(map[string]any)
+		for i, doc := range docs {
+			m, ok := doc.(map[string]any)
+			if !ok && doc != nil {
+				return nil, fmt.Errorf("document %d is not a map", i+1)
+			}
+			for k, v := range m {
+				data[k] = v
+			}
+		}
+		return data, nil
+	case YamlDocumentsList:
+		return nil, ErrMountRequired
+	default:
+		return nil, fmt.Errorf("unsupported documents mode: %s", ds.documents)
+	}
+}
+
+func (ds *YamlDatasource) LoadValue() (any, error) {
+	switch ds.documents {
+	case YamlDocumentsSingle, YamlDocumentsFirst:
+		var data any
+		if err := ds.decodeFirst(&data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	case YamlDocumentsList:
+		return ds.decodeAll()
+	default:
+		return ds.Load()
+	}
+}
+
+// decodeFirst decodes the first document into v. Unless only the first document is requested,
+// the stream must not have other non-empty documents, so that they are not silently ignored.
+func (ds *YamlDatasource) decodeFirst(v any) error {
+	decoder := yaml.NewDecoder(ds.r)
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if ds.documents == YamlDocumentsFirst {
+		return nil
+	}
+	for {
+		var doc any
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if doc != nil {
+			return ErrMultipleDocuments
+		}
+	}
+}
+
+func (ds *YamlDatasource) decodeAll() ([]any, error) {
+	docs := []any{}
 	decoder := yaml.NewDecoder(ds.r)
-	if err := decoder.Decode(&data); err != nil {
-		return nil, err
+	for {
+		var doc any
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
 	}
-	return data, nil
 }
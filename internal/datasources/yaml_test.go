@@ -22,3 +22,81 @@ key2: 5`
 	require.NoError(t, err)
 	require.Equal(t, expectedData, data)
 }
+
+const multiDocumentYamlData = `
+key1: value1
+key2: 5
+---
+key2: 6
+key3: value3
+`
+
+func TestYamlLoadMultipleDocumentsFirst(t *testing.T) {
+	expectedData := map[string]any{
+		"key1": "value1",
+		"key2": 5,
+	}
+	ds := NewMultiDocumentYamlDatasource(strings.NewReader(multiDocumentYamlData), YamlDocumentsFirst)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestYamlLoadMultipleDocumentsUnset(t *testing.T) {
+	// Other documents are not silently ignored unless only the first one is requested
+	ds := NewYamlDatasource(strings.NewReader(multiDocumentYamlData))
+	_, err := ds.Load()
+	require.ErrorIs(t, err, ErrMultipleDocuments)
+
+	ds = NewYamlDatasource(strings.NewReader(multiDocumentYamlData))
+	_, err = ds.LoadValue()
+	require.ErrorIs(t, err, ErrMultipleDocuments)
+
+	// Empty documents, such as after a trailing separator, are not documents to load
+	ds = NewYamlDatasource(strings.NewReader("key1: value1\n---\n---\n"))
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"key1": "value1"}, data)
+}
+
+func TestYamlLoadMultipleDocumentsMerge(t *testing.T) {
+	expectedData := map[string]any{
+		"key1": "value1",
+		"key2": 6,
+		"key3": "value3",
+	}
+	ds := NewMultiDocumentYamlDatasource(strings.NewReader(multiDocumentYamlData), YamlDocumentsMerge)
+
+	data, err := ds.Load()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	ds = NewMultiDocumentYamlDatasource(strings.NewReader("key1: value1\n---\n- item"), YamlDocumentsMerge)
+	_, err = ds.Load()
+	require.Error(t, err)
+}
+
+func TestYamlLoadMultipleDocumentsList(t *testing.T) {
+	expectedData := []any{
+		map[string]any{"key1": "value1", "key2": 5},
+		map[string]any{"key2": 6, "key3": "value3"},
+	}
+	ds := NewMultiDocumentYamlDatasource(strings.NewReader(multiDocumentYamlData), YamlDocumentsList)
+
+	data, err := ds.LoadValue()
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	ds = NewMultiDocumentYamlDatasource(strings.NewReader(multiDocumentYamlData), YamlDocumentsList)
+	_, err = ds.Load()
+	require.ErrorIs(t, err, ErrMountRequired)
+}
+
+func TestYamlLoadValueScalar(t *testing.T) {
+	ds := NewYamlDatasource(strings.NewReader("v1.2.0"))
+
+	data, err := ds.LoadValue()
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.0", data)
+}